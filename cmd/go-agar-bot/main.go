@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go-agar/pkg/client"
+	"go-agar/pkg/protocol"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+//an example bot, it eats the closest food and prints what happens around it
+func main() {
+	addr := flag.String("url", "ws://localhost:38888/game", "game endpoint")
+	name := flag.String("name", "bot", "player name")
+	room := flag.String("room", "", "room id to join")
+	flag.Parse()
+
+	statuses := make(chan *protocol.PlayerStatus, 1)
+	died := make(chan struct{}, 1)
+	var respawnDelay int64
+	options := client.DefaultOptions(*name)
+	options.Room = *room
+	c, e := client.Dial(*addr, options, &client.Handler{
+		GameSetup: func(s *protocol.GameSetup) {
+			fmt.Printf("joined room %s, map %.0fx%.0f\n", s.RoomName, s.Width, s.Height)
+			atomic.StoreInt64(&respawnDelay, s.RespawnDelay)
+		},
+		Chat: func(c *protocol.Chat) {
+			fmt.Println(c.Data)
+		},
+		LeaderBoard: func(l *protocol.LeaderBoard) {
+			fmt.Println("leaders:", l.Names)
+		},
+		Death: func(d *protocol.Death) {
+			killer := d.Killer
+			if killer == "" {
+				killer = "nobody"
+			}
+			fmt.Printf("eaten by %s after %ds, peak mass %.0f\n", killer, d.TimeAlive/1000, d.PeakMass)
+			died <- struct{}{}
+		},
+		Kill: func(k *protocol.Kill) {
+			fmt.Printf("%s ate %s\n", k.Killer, k.Victim)
+		},
+		Achievement: func(a *protocol.Achievement) {
+			fmt.Printf("achievement unlocked: %s, %s\n", a.Name, a.Description)
+		},
+		Error: func(e *protocol.Error) {
+			fmt.Printf("server error %d: %s\n", e.Code, e.Message)
+		},
+		PlayerStatus: func(s *protocol.PlayerStatus) {
+			//only the latest status is worth acting on
+			select {
+			case <-statuses:
+			default:
+			}
+			statuses <- s
+		},
+	})
+	if e != nil {
+		println("dial error", e.Error())
+		return
+	}
+	defer c.Close()
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	var latest *protocol.PlayerStatus
+	var respawn <-chan time.Time
+	for {
+		select {
+		case <-c.Done():
+			fmt.Println("disconnected:", c.Err())
+			return
+		case <-died:
+			latest = nil
+			respawn = time.After(time.Duration(atomic.LoadInt64(&respawnDelay)) * time.Millisecond)
+		case <-respawn:
+			respawn = nil
+			if c.Respawn() != nil {
+				return
+			}
+		case s := <-statuses:
+			latest = s
+		case <-ticker.C:
+			if latest == nil {
+				continue
+			}
+			x, y := closestFood(latest)
+			if _, e := c.Move(x, y); e != nil {
+				return
+			}
+		}
+	}
+}
+
+func closestFood(s *protocol.PlayerStatus) (float64, float64) {
+	x, y, best := 0.0, 0.0, math.MaxFloat64
+	for _, f := range s.Foods {
+		if d := math.Hypot(f.X-s.X, f.Y-s.Y); d < best {
+			x, y, best = f.X-s.X, f.Y-s.Y, d
+		}
+	}
+	return x, y
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"go-agar/pkg/client"
+	"go-agar/pkg/protocol"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	strategyRandom = "random"
+	strategyGreedy = "greedy"
+)
+
+type bot struct {
+	url      string
+	name     string
+	strategy string
+	insecure bool
+	stats    *stats
+	client   *client.Client
+	locker   *sync.Mutex
+	latest   *protocol.PlayerStatus
+	//respawnDelay comes with the game setup, respawnAt is set while the player is dead
+	respawnDelay time.Duration
+	respawnAt    time.Time
+	targetX      float64
+	targetY      float64
+}
+
+func newBot(url, name, strategy string, insecure bool, stats *stats) *bot {
+	return &bot{
+		url:      url,
+		name:     name,
+		strategy: strategy,
+		insecure: insecure,
+		stats:    stats,
+		locker:   &sync.Mutex{},
+	}
+}
+
+func (b *bot) play(duration time.Duration) {
+	options := client.DefaultOptions(b.name)
+	options.PingInterval = 0
+	options.Insecure = b.insecure
+	c, e := client.Dial(b.url, options, &client.Handler{
+		Message: b.stats.received,
+		GameSetup: func(s *protocol.GameSetup) {
+			b.stats.setupReceived()
+			b.locker.Lock()
+			b.respawnDelay = time.Duration(s.RespawnDelay) * time.Millisecond
+			b.locker.Unlock()
+		},
+		PlayerStatus: func(s *protocol.PlayerStatus) {
+			b.stats.statusLatency(time.Since(client.ServerTime(s.Time)))
+			b.locker.Lock()
+			b.latest = s
+			b.locker.Unlock()
+		},
+		Death: func(*protocol.Death) {
+			b.stats.died()
+			b.locker.Lock()
+			b.latest = nil
+			b.respawnAt = time.Now().Add(b.respawnDelay)
+			b.locker.Unlock()
+		},
+		Malformed: func(action string, e error) {
+			if action == protocol.ActionPlayerStatus {
+				b.stats.badFrame()
+			}
+		},
+	})
+	if e != nil {
+		b.stats.connectFailed()
+		return
+	}
+	b.client = c
+	b.stats.connected()
+	defer c.Close()
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	for {
+		select {
+		case <-c.Done():
+			b.stats.disconnected()
+			return
+		case <-timer.C:
+			return
+		case <-ticker.C:
+			if !b.act() {
+				b.stats.disconnected()
+				return
+			}
+		}
+	}
+}
+
+func (b *bot) act() bool {
+	b.locker.Lock()
+	s := b.latest
+	respawnAt := b.respawnAt
+	b.locker.Unlock()
+	if !respawnAt.IsZero() {
+		return b.respawn(respawnAt)
+	}
+	switch b.strategy {
+	case strategyGreedy:
+		if s != nil {
+			b.targetX, b.targetY = greedyTarget(s)
+		}
+	default:
+		if rand.Intn(20) == 0 {
+			b.targetX = rand.Float64()*600 - 300
+			b.targetY = rand.Float64()*400 - 200
+		}
+	}
+	if _, e := b.client.Move(b.targetX, b.targetY); e != nil {
+		return false
+	}
+	b.stats.sent()
+	switch rand.Intn(100) {
+	case 0:
+		return b.sent(b.client.Split())
+	case 1:
+		return b.sent(b.client.Fire())
+	}
+	return true
+}
+
+//respawn waits out the delay of a dead player, then asks for a new one
+func (b *bot) respawn(at time.Time) bool {
+	if time.Now().Before(at) {
+		return true
+	}
+	b.locker.Lock()
+	b.respawnAt = time.Time{}
+	b.locker.Unlock()
+	b.stats.respawned()
+	return b.sent(b.client.Respawn())
+}
+
+func (b *bot) sent(e error) bool {
+	if e != nil {
+		return false
+	}
+	b.stats.sent()
+	return true
+}
+
+//greedyTarget chases the closest smaller cell, or else the closest food
+func greedyTarget(s *protocol.PlayerStatus) (float64, float64) {
+	biggest := 0.0
+	for _, c := range s.Cells {
+		if c.Mine {
+			biggest = math.Max(biggest, c.Radius)
+		}
+	}
+	found, x, y, bestDist := false, 0.0, 0.0, math.MaxFloat64
+	for _, c := range s.Cells {
+		if c.Mine || c.Radius*1.1 >= biggest {
+			continue
+		}
+		if d := math.Hypot(c.X-s.X, c.Y-s.Y); d < bestDist {
+			found, x, y, bestDist = true, c.X, c.Y, d
+		}
+	}
+	if !found {
+		for _, f := range s.Foods {
+			if d := math.Hypot(f.X-s.X, f.Y-s.Y); d < bestDist {
+				found, x, y, bestDist = true, f.X, f.Y, d
+			}
+		}
+	}
+	if !found {
+		return 0, 0
+	}
+	return x - s.X, y - s.Y
+}
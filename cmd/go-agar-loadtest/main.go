@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+func main() {
+	addr := flag.String("url", "ws://localhost:38888/game", "game endpoint")
+	num := flag.Int("n", 50, "number of connections")
+	duration := flag.Duration("d", 30*time.Second, "how long each bot plays")
+	ramp := flag.Duration("ramp", 20*time.Millisecond, "delay between two connections")
+	strategy := flag.String("strategy", "random", "random or greedy")
+	insecure := flag.Bool("insecure", false, "accept any tls certificate")
+	flag.Parse()
+	if *strategy != strategyRandom && *strategy != strategyGreedy {
+		println("unknown strategy", *strategy)
+		return
+	}
+	rand.Seed(time.Now().UnixNano())
+
+	stats := newStats()
+	wg := &sync.WaitGroup{}
+	start := time.Now()
+	for i := 0; i < *num; i++ {
+		b := newBot(*addr, "bot"+strconv.Itoa(i), *strategy, *insecure, stats)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.play(*duration)
+		}()
+		time.Sleep(*ramp)
+	}
+	wg.Wait()
+	fmt.Print(stats.report(time.Since(start)))
+}
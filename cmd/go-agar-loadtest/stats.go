@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type stats struct {
+	locker        *sync.Mutex
+	connects      int
+	connectFails  int
+	setups        int
+	disconnects   int
+	deaths        int
+	respawns      int
+	badFrames     int
+	sentMessages  int
+	receivedBytes int
+	messages      map[string]int
+	latencies     []time.Duration
+}
+
+func newStats() *stats {
+	return &stats{
+		locker:   &sync.Mutex{},
+		messages: make(map[string]int),
+	}
+}
+
+func (s *stats) connected() {
+	s.locker.Lock()
+	s.connects++
+	s.locker.Unlock()
+}
+
+func (s *stats) connectFailed() {
+	s.locker.Lock()
+	s.connectFails++
+	s.locker.Unlock()
+}
+
+func (s *stats) setupReceived() {
+	s.locker.Lock()
+	s.setups++
+	s.locker.Unlock()
+}
+
+func (s *stats) disconnected() {
+	s.locker.Lock()
+	s.disconnects++
+	s.locker.Unlock()
+}
+
+func (s *stats) died() {
+	s.locker.Lock()
+	s.deaths++
+	s.locker.Unlock()
+}
+
+func (s *stats) respawned() {
+	s.locker.Lock()
+	s.respawns++
+	s.locker.Unlock()
+}
+
+func (s *stats) badFrame() {
+	s.locker.Lock()
+	s.badFrames++
+	s.locker.Unlock()
+}
+
+func (s *stats) sent() {
+	s.locker.Lock()
+	s.sentMessages++
+	s.locker.Unlock()
+}
+
+func (s *stats) received(msgType string, size int) {
+	s.locker.Lock()
+	s.messages[msgType]++
+	s.receivedBytes += size
+	s.locker.Unlock()
+}
+
+func (s *stats) statusLatency(d time.Duration) {
+	s.locker.Lock()
+	s.latencies = append(s.latencies, d)
+	s.locker.Unlock()
+}
+
+func (s *stats) report(elapsed time.Duration) string {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	var sb strings.Builder
+	seconds := elapsed.Seconds()
+	fmt.Fprintf(&sb, "elapsed            %s\n", elapsed.Round(time.Millisecond))
+	fmt.Fprintf(&sb, "connections        %d ok, %d failed, %d joined a battle\n", s.connects, s.connectFails, s.setups)
+	fmt.Fprintf(&sb, "server disconnects %d\n", s.disconnects)
+	fmt.Fprintf(&sb, "deaths %d, respawns %d\n", s.deaths, s.respawns)
+	fmt.Fprintf(&sb, "sent               %d msgs (%.1f/s)\n", s.sentMessages, float64(s.sentMessages)/seconds)
+	total := 0
+	types := make([]string, 0, len(s.messages))
+	for t, n := range s.messages {
+		types = append(types, t)
+		total += n
+	}
+	sort.Strings(types)
+	fmt.Fprintf(&sb, "received           %d msgs (%.1f/s), %.1f KB/s\n", total, float64(total)/seconds, float64(s.receivedBytes)/1024/seconds)
+	for _, t := range types {
+		fmt.Fprintf(&sb, "  action %s         %d (%.1f/s)\n", t, s.messages[t], float64(s.messages[t])/seconds)
+	}
+	fmt.Fprintf(&sb, "bad status frames  %d\n", s.badFrames)
+	if len(s.latencies) > 0 {
+		sort.Slice(s.latencies, func(i, j int) bool {
+			return s.latencies[i] < s.latencies[j]
+		})
+		fmt.Fprintf(&sb, "status latency     p50 %s, p90 %s, p99 %s, max %s\n",
+			s.percentile(0.5), s.percentile(0.9), s.percentile(0.99), s.latencies[len(s.latencies)-1])
+	}
+	return sb.String()
+}
+
+func (s *stats) percentile(p float64) time.Duration {
+	return s.latencies[int(float64(len(s.latencies)-1)*p)].Round(time.Microsecond)
+}
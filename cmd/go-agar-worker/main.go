@@ -0,0 +1,20 @@
+package main
+
+import (
+	"flag"
+	"go-agar/internal/game"
+	"go-agar/internal/gateway"
+)
+
+func main() {
+	listen := flag.String("listen", game.Config.WorkerListen, "address the gateway connects to")
+	flag.Parse()
+	g, e := gateway.NewGateway()
+	if e != nil {
+		game.Log.WithError(e).Error("gateway init error")
+		return
+	}
+	if e := g.RunWorker(*listen); e != nil {
+		game.Log.WithError(e).Error("worker stop")
+	}
+}
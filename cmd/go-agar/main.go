@@ -1,13 +1,14 @@
 package main
 
 import (
+	"go-agar/internal/game"
 	"go-agar/internal/gateway"
 )
 
 func main() {
 	g, e := gateway.NewGateway()
 	if e != nil {
-		println("gateway init error", e)
+		game.Log.WithError(e).Error("gateway init error")
 		return
 	}
 	g.Run()
@@ -1,11 +1,12 @@
 module go-agar
 
-go 1.13
+go 1.16
 
 require (
 	github.com/gin-gonic/gin v1.5.0
 	github.com/gorilla/websocket v1.4.0
 	github.com/satori/go.uuid v1.2.0
+	github.com/sirupsen/logrus v1.4.2
 	github.com/spf13/viper v1.6.1
-	golang.org/x/tools v0.0.0-20191029190741-b9c20aec41a5 // indirect
+	golang.org/x/text v0.3.0
 )
@@ -1,292 +1,204 @@
-// Code generated for package asset by go-bindata DO NOT EDIT. (@generated)
-// sources:
-// web/game.css
-// web/game.js
-// web/index.html
+//Package asset serves the web client with validators, caching headers and compression
 package asset
 
 import (
 	"bytes"
 	"compress/gzip"
-	"fmt"
-	"io"
-	"io/ioutil"
-	"os"
-	"path/filepath"
+	"crypto/sha1"
+	"encoding/hex"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
-func bindataRead(data []byte, name string) ([]byte, error) {
-	gz, err := gzip.NewReader(bytes.NewBuffer(data))
-	if err != nil {
-		return nil, fmt.Errorf("Read %q: %v", name, err)
-	}
-
-	var buf bytes.Buffer
-	_, err = io.Copy(&buf, gz)
-	clErr := gz.Close()
-
-	if err != nil {
-		return nil, fmt.Errorf("Read %q: %v", name, err)
-	}
-	if clErr != nil {
-		return nil, err
+//files smaller than this are not worth compressing
+const minGzipSize = 512
+
+type file struct {
+	name        string
+	data        []byte
+	gzipped     []byte
+	etag        string
+	contentType string
+	modTime     time.Time
+	size        int64
+}
+
+//Server serves the files of fsys, with live set every request reads fsys again so edits show up at once,
+//otherwise files are cached until their size or modification time changes
+type Server struct {
+	fsys   fs.FS
+	live   bool
+	maxAge time.Duration
+	files  map[string]*file
+	locker *sync.RWMutex
+}
+
+func NewServer(fsys fs.FS, live bool, maxAge time.Duration) *Server {
+	return &Server{
+		fsys:   fsys,
+		live:   live,
+		maxAge: maxAge,
+		files:  make(map[string]*file),
+		locker: &sync.RWMutex{},
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	f, e := s.open(r.URL.Path)
+	if e != nil {
+		http.NotFound(w, r)
+		return
+	}
+	header := w.Header()
+	header.Set("Content-Type", f.contentType)
+	header.Set("Cache-Control", s.cacheControl(f))
+	body, etag := f.data, f.etag
+	if f.gzipped != nil {
+		header.Add("Vary", "Accept-Encoding")
+		if acceptsGzip(r) {
+			header.Set("Content-Encoding", "gzip")
+			body, etag = f.gzipped, f.etag[:len(f.etag)-1]+`-gzip"`
+		}
 	}
-
-	return buf.Bytes(), nil
-}
-
-type asset struct {
-	bytes []byte
-	info  os.FileInfo
-}
-
-type bindataFileInfo struct {
-	name    string
-	size    int64
-	mode    os.FileMode
-	modTime time.Time
-}
-
-// Name return file name
-func (fi bindataFileInfo) Name() string {
-	return fi.name
-}
-
-// Size return file size
-func (fi bindataFileInfo) Size() int64 {
-	return fi.size
-}
-
-// Mode return file mode
-func (fi bindataFileInfo) Mode() os.FileMode {
-	return fi.mode
-}
-
-// Mode return file modify time
-func (fi bindataFileInfo) ModTime() time.Time {
-	return fi.modTime
-}
-
-// IsDir return file whether a directory
-func (fi bindataFileInfo) IsDir() bool {
-	return fi.mode&os.ModeDir != 0
-}
-
-// Sys return file is sys mode
-func (fi bindataFileInfo) Sys() interface{} {
-	return nil
+	header.Set("ETag", etag)
+	//ServeContent answers If-None-Match, ranges and HEAD
+	http.ServeContent(w, r, f.name, f.modTime, bytes.NewReader(body))
 }
 
-var _webGameCss = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xb4\x56\xcd\x6e\xe3\x36\x10\xbe\x1b\xf0\x3b\x4c\xd7\x28\xb0\x49\x2d\xaf\x2c\xc7\x59\xaf\x72\x6a\x9b\x06\xd8\x43\x7b\xed\x99\x92\x46\x16\x11\x8a\x54\x49\xca\x3f\x29\xf6\xc9\x7a\xeb\x93\x2d\x44\xfd\x53\x92\x95\xcb\x3a\x81\x61\x91\xc3\x8f\xf3\xf3\xcd\xa7\xb9\x87\x7f\x97\x0b\x00\x80\x58\x70\xed\xc4\x24\xa5\xec\xea\x83\x22\x5c\x39\x0a\x25\x8d\x9f\x3a\xbb\x8a\xbe\xa1\x0f\xdb\x87\xec\xf2\xb4\x5c\x7c\x5b\x2e\x96\x8b\x44\xa7\x6c\x0d\x81\x88\xae\x35\x4c\x40\xc2\xd7\xa3\x14\x39\x8f\x9c\x50\x30\x21\x7d\x58\x79\x9e\x57\xa1\x88\x13\xca\x98\x89\xb3\x0f\x09\x8d\x22\xe4\x43\x98\x35\x84\x84\x9f\x88\xaa\xe1\xce\x34\xd2\x89\x0f\x5b\xd7\xfd\xb9\xc2\x48\x90\x1e\x13\xdd\x5b\x4a\x89\x3c\x52\xee\x83\x5b\x3d\x67\x24\x8a\x28\x3f\x96\x0b\xe6\x82\x88\x9e\x6a\x44\xe7\x8c\xc1\x2b\xd5\x4e\xae\x50\x3a\x0a\x19\x86\xda\x07\x2e\x38\x3e\xc1\xa7\x7b\x28\x37\xe1\xa3\x22\x31\x91\x74\x0d\x61\x22\x45\x8a\x77\x10\x48\x71\x56\x28\x15\xdc\x7f\xaa\x60\x52\xf1\x36\x81\x91\x8a\x37\xca\x18\x19\x39\xf3\x5a\x04\x3a\x73\xf3\xab\xe0\xff\xe4\x28\x85\x1c\xbd\x54\x4d\x9c\xfe\xfa\xc7\xd6\xfd\xc5\xd8\x99\x78\x57\x4a\x13\x9d\x37\x59\xcc\x84\xa2\x9a\x0a\xee\x03\x09\x94\x60\xb9\x46\x3b\x53\x5b\xd7\x14\xb5\x5f\x41\x1f\xe4\x31\x20\x1f\xdd\x35\x54\xff\x9b\x87\xbb\xca\xa8\xae\xed\xcb\xcb\xcb\x08\x43\x1e\x37\xdb\x06\x4e\x8b\xac\x07\x2f\xeb\xf2\x35\x2b\xe6\xe4\xb9\x2a\x6b\x20\x58\x54\x9f\xc4\x8b\x76\x08\xa3\x47\xee\x43\x88\x5c\xa3\x7c\xb2\xc2\xdb\x68\xaa\x19\xf6\x18\x5c\x7a\xe0\xed\x5b\x8e\x36\xc6\x69\x63\xd9\x7a\x7f\x38\x1c\x0e\x37\x02\x30\x00\x9b\x30\x21\x3a\x10\x97\xd9\x6c\x56\x64\xdd\xb9\x6d\x6c\x35\x5b\x77\xde\x8d\x04\x7b\xfb\xfd\x1a\xda\x2f\x77\xf3\xb9\x4e\x73\x20\xb4\x16\xa9\x0f\xfb\xe6\x30\xc3\x58\x77\x9f\x03\x21\x23\x94\x8e\x24\x11\xcd\x55\x77\x23\x13\xb4\x48\x99\x83\x27\xe4\x5a\x55\x54\xb1\x23\x32\x3f\x1c\x46\x95\x6e\x82\xab\x19\xd1\x22\xd9\xed\x55\x98\x3b\x4a\x5f\x19\x36\xa8\xa5\x27\x97\x22\x7d\xe6\x70\xe5\x55\x20\xec\x3c\x78\x87\x16\x77\x4a\x0d\xc6\xbc\x63\x74\xe0\xa0\x37\x70\x70\x37\x52\xb3\x1e\xc6\x26\x63\xe4\x8a\x12\x02\x9b\x08\x48\x1e\xb7\xfb\xdd\xcc\x61\x75\x55\x1a\x53\xfb\xe8\x97\x60\xff\x25\x78\xec\x71\xa8\x4c\x0d\xd5\x84\xd1\xf0\x5d\x98\x7e\x80\xb1\x90\x1d\x7a\x72\x8d\x5c\xfb\xf0\xe1\xff\xff\xe0\xc3\x04\x02\xe5\x59\xde\x56\xcd\x2a\x36\x61\xec\x3d\x55\x19\xaa\x6b\x93\xdd\xc3\x28\x5d\xb5\x24\x5c\x65\x44\x22\xd7\x3d\xfe\x59\x44\x30\xd7\x94\x8d\x9f\x5d\x40\x09\x46\x23\x58\x3d\x3f\x3f\xd7\x85\xcf\x35\xa3\x1c\xfb\x9c\x2c\xda\x54\xea\x3f\x91\xe7\xc3\x3e\x93\xc8\x88\xa6\x27\xb4\xea\xbd\x2d\x1a\x0d\x48\xae\x85\xf9\xb2\xba\x70\xdf\x76\x5c\xcb\x19\x77\xbe\x71\x8c\xb2\x4f\xef\x56\xaf\x8f\x49\x83\xe1\x0b\xf0\x9c\x50\x3d\xdb\x24\x76\x0e\xb2\x01\xdf\xdd\x5b\xb2\x68\x09\xd8\xc5\x61\x44\x1e\xf1\x86\xc0\x96\xf7\x95\xfd\xf0\x17\x49\xf1\x6b\x97\x4e\x43\x5e\x4c\x5e\x3a\xfa\x06\xa9\x38\x51\x16\xbe\xa0\xc0\x2a\x0a\x8b\xbf\x1a\xac\x60\x51\x55\x5a\x93\x90\x84\x44\xe2\x0c\xee\x66\xa7\xd6\xd5\x61\xf3\xf0\x1e\x0a\xff\xa8\x32\x96\x1c\x73\x6a\x01\xee\x44\x37\xce\x5e\x2b\x93\x7e\x2c\xc2\x5c\xad\xc1\x5e\xdf\x98\xf5\x66\x58\x1a\x26\xea\x77\xf3\xe9\x46\x6e\x92\xe3\x83\x0b\x6e\x21\x70\xa5\xd5\xb3\xf9\xf4\x69\xf3\x9b\xe6\x73\x8d\x33\x3d\x4d\x3d\x74\xab\x77\x23\xdd\x1d\x8e\x75\x19\x36\xc2\xf4\x49\xc6\x98\x8d\x36\xaa\x22\x1e\x2f\xbb\x0c\x46\x0d\x6f\x7f\x37\x22\x40\xab\xcf\x71\xb4\x47\x62\x11\xcd\xed\x93\xa1\x2e\x9a\xd7\x8a\xcf\xe1\x10\x85\xcd\xb1\x30\x97\xaa\x70\xb7\x52\xcd\x01\x25\xda\xa4\x53\xae\x50\x83\x0b\x4e\x01\xd5\x07\x79\xb7\xd9\x8f\xe4\x67\x49\xcc\x5a\xfd\xfa\x64\xf0\x49\x58\x94\x7e\x0d\xed\x4a\x52\xbc\x72\x6b\xf2\xd5\xfa\x3c\x96\xe5\x43\x88\x41\x1c\x4f\x10\x7e\x2a\x59\xf6\x2c\xd0\x5f\xb6\x04\xee\x6f\x49\xb2\xac\x75\xa6\xc6\xeb\x4a\x43\x4a\x2e\x4e\xc9\x4f\xd8\xaa\x6e\xd6\xe6\x8d\xd4\xbc\x8d\x98\x35\x99\xdb\x9f\x9a\x5f\x3a\x2a\x9e\xec\x2c\x19\x6f\xb8\xe9\x8e\xeb\x8c\x3b\x02\x92\xb3\x1a\xa4\x5b\xf4\x1b\xfa\x5b\x61\x9a\x02\x8f\x01\x6e\xcc\xec\xe0\xa0\x94\x42\x5a\xe3\x8c\xc4\x7a\xfa\x16\x19\x09\xa9\xbe\xb6\x9e\x36\xad\x0f\x3e\x6c\xbd\x6c\xe4\xa5\x65\xd5\xf4\xcd\xa1\x3c\xc2\x8b\x0f\x5e\x63\x7a\x24\x29\xfe\x2a\x91\x58\x96\xc3\x89\x1a\x7e\xa2\x69\x26\xa4\x26\xcd\xa0\xd1\x44\xd3\x4e\xc1\xee\x98\xab\xe6\xa2\x7b\xbf\xa7\xb3\x0d\x83\xdb\x71\x64\x6a\x92\xb1\x06\xdb\x6f\xcb\xc5\xf7\x00\x00\x00\xff\xff\xd3\xd5\x80\xbb\x22\x0f\x00\x00")
-
-func webGameCssBytes() ([]byte, error) {
-	return bindataRead(
-		_webGameCss,
-		"web/game.css",
-	)
-}
-
-func webGameCss() (*asset, error) {
-	bytes, err := webGameCssBytes()
-	if err != nil {
-		return nil, err
+//pages must be revalidated so a deploy shows up at once, what they link may be cached
+func (s *Server) cacheControl(f *file) string {
+	if s.live || s.maxAge <= 0 || strings.HasSuffix(f.name, ".html") {
+		return "no-cache"
 	}
-
-	info := bindataFileInfo{name: "web/game.css", size: 3874, mode: os.FileMode(438), modTime: time.Unix(1578238434, 0)}
-	a := &asset{bytes: bytes, info: info}
-	return a, nil
+	return "public, max-age=" + strconv.Itoa(int(s.maxAge.Seconds()))
 }
 
-var _webGameJs = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xe4\x3b\x6d\x6f\x1b\x37\xd2\xdf\x03\xe4\x3f\x4c\xf4\x14\xd8\x55\xa4\xac\x25\x39\x4d\xf3\xc8\x91\x8b\xd8\x4d\xda\xa0\x49\x1b\xd4\xc1\xa5\x39\xc3\x87\x52\xbb\x5c\x89\xe7\xd5\x52\x47\x72\xf5\x92\x58\xff\xfd\x40\x72\x5f\xb8\x5c\xae\x64\xd7\x0d\x0e\x87\xcb\x07\x59\x22\xe7\x8d\x33\xc3\xe1\xcc\x90\x09\x69\xca\x05\xbc\x0c\x05\xa1\xe9\x2b\xc6\x28\x83\x09\x74\x06\x83\x4e\xff\xe1\x03\x00\xc8\x27\xde\x93\x74\xa6\xc6\x87\xf5\xf1\x1f\xd1\x02\x5f\x60\x91\x2d\xd5\xe4\xa8\x3e\x79\x3e\x47\x42\x8d\x1f\x5b\xc4\x12\xb4\xc5\xec\x42\x20\x91\x71\x35\xff\xb4\x3e\xff\x8e\xae\xb0\x1a\xff\xb6\x3e\xfe\x9a\x30\x3d\xfe\xac\x3e\x7e\xb1\x4c\x88\x66\xf4\x5d\x7d\xe2\x2d\x46\x11\x66\x67\x14\xb1\x48\x4d\x3f\xef\x9c\x3c\x7c\xf0\xf0\x81\x5e\xf1\x2c\xa1\x53\x94\xc0\x04\xbe\x68\x94\x08\x4f\xb3\xd9\x18\x62\x94\x70\x9c\x53\x99\xa1\x05\xfe\x48\x22\x31\x1f\xc3\xc0\x18\xfa\x09\x93\xd9\x5c\x54\x63\x3c\x64\x18\xa7\x39\xe0\x9a\xa4\x11\x5d\x07\x24\x4d\x31\x53\x43\x35\xa0\x02\xd5\x84\xd2\x63\x39\x58\x42\x52\x7c\x4e\x13\xca\xc6\xe0\xfd\xdf\x40\xfd\xf3\xf2\xa9\x29\x0a\xaf\x67\x8c\x66\x69\x54\x02\xc4\xa3\x78\x1a\xc7\x05\xc0\x8a\xb0\x8c\x97\x73\xdf\x4d\xe3\xf8\xd9\xb3\x62\x8e\x91\xe5\x47\x44\xc4\x05\x0e\x69\x1a\xf1\x31\x1c\xf7\x1f\x3e\xd8\x19\xda\x08\x13\x82\x53\x51\x69\x43\x20\x36\xc3\xe2\xf7\x6a\x91\x7a\xe0\x53\x35\xb0\x54\x56\x1c\x43\x96\x46\x38\x26\x29\x8e\xf2\xf1\x35\x6f\x8e\x2d\x49\x3a\xfb\x40\x16\xd8\x3d\xd3\x1c\x45\x29\x59\xbc\xa5\x74\xf9\x13\x4a\xa3\xc4\x81\x25\xad\x20\xe7\xcf\x69\x96\xe6\x86\xa8\x2d\x06\xa5\x2b\x24\x5d\x2b\xa2\x61\xb6\xc0\xa9\x08\x66\x58\xbc\x4a\xb0\xfc\x7a\xb6\x7d\x13\xf9\x1d\x49\xa0\xd3\x2d\xa8\x31\xb4\x9c\xc3\x24\x47\x93\xb0\xe7\x34\x15\x78\x23\xfc\xce\x28\xea\x74\x4d\xc2\x34\x15\x8c\x26\x09\x66\x95\xa6\x48\x4a\x84\xdf\x2d\x7e\x29\x4f\x6a\xe1\xea\x71\x81\x98\x38\x13\xa9\xd7\x0d\x68\x1a\x26\x24\xbc\x96\x5c\x4b\x9a\x81\x9a\x3f\xa9\x08\xe5\x5e\x42\xd3\x6b\xbc\x5d\x32\xcc\x79\x1d\x9c\xa6\x3f\xe7\xe3\x06\x4e\xbe\x08\x14\x45\xaf\x56\x38\x15\x6f\x09\x17\x38\xc5\xcc\xef\x2c\x68\xc6\xf1\x82\xae\x70\xa7\x5f\x27\xf2\x4e\x4e\xc8\x3d\xd7\xbd\x35\x19\x9a\x09\x27\x95\x5f\x33\xa1\xd5\x65\xad\x80\xe1\x7f\x65\x98\x8b\x97\x29\x59\xbc\x66\x68\x21\x77\xb1\x1f\x67\xa9\xda\xa4\x50\x57\x9e\x72\x56\x2c\x32\x96\x3a\x90\x91\x0a\x03\x8a\xc2\xcd\x4d\x1d\xc7\xe0\xb6\xc6\xd3\x6b\x22\x7e\xbb\x2b\xda\x82\x7e\xbe\x3b\x0e\xbf\x33\x0a\xfd\xaa\x4c\x2a\xad\x86\x28\x49\x64\xc8\x68\x68\xd7\xa2\xce\xb1\x90\x5b\x93\x66\xa2\x44\xe9\xc3\x70\x30\x18\xc0\x11\x3c\x1b\x98\x3e\x51\xfc\xdb\x19\x63\xbb\xae\xef\xb4\x78\x88\xd2\x10\x27\x2d\x06\x9f\xab\x6d\x7d\xc0\xec\x15\x85\x5b\x9b\xef\xdc\x81\xd2\x94\x55\x7d\x2d\xa2\xb2\xdc\x71\x75\x07\x4c\xb0\x80\x54\x8b\xdc\xba\x91\x75\xec\xfb\x05\x2d\xf0\x9b\x74\x99\x09\xaf\x1b\xac\x50\x92\x99\xbc\x48\x0c\xbe\xa6\x32\x99\x80\xe7\xb5\xac\xd5\x90\xad\x2e\xc0\x92\x51\x41\x43\x2a\x8f\xa7\x7c\x79\x09\x0d\xd5\xa2\x82\x6a\x4a\x52\x9e\x0b\xb1\xe4\x63\x0f\xbe\x07\x6f\xcd\xb9\x07\x63\xf9\xd7\x3b\xa9\x53\x5b\xcb\xc0\x91\xe2\x35\x7c\xc4\xd3\x0b\x1a\x5e\x63\xe1\xff\xf1\xcd\x97\x82\xd0\x6e\x7c\x74\xf4\xcd\x17\x9b\xcd\x9c\x72\xb1\x3b\x92\x71\xf2\x7b\xb9\x8e\xc9\x37\x5f\xe4\x9f\xdd\x1f\xa6\x43\xac\x79\x40\x53\xba\xc4\x29\x4c\x00\xaf\x04\x4c\x4e\xed\x65\xb6\x6a\x50\x12\x7e\xc9\x30\xfa\xc8\xd0\x72\x89\x99\xd7\x0d\xb8\xd8\x26\x38\xa0\x4b\x14\x12\xb1\x85\x09\x0c\x4f\x6e\x49\x4a\xd9\xf0\x1d\x4e\x33\x9b\xd6\x02\x6d\xf4\xd9\x0a\x13\xf0\x06\xcb\x8d\x57\x73\x54\xf9\x4f\x9f\x7a\x81\xd2\xcf\x9a\x5b\x0c\x8d\xf0\x56\x9c\x37\xbe\xb9\xfa\x9d\xad\x89\x05\xe6\x1c\xcd\x70\xa5\x0c\xed\xe7\x2c\xd0\x7f\x7d\xbc\x12\x41\x84\x04\x6a\xa8\x30\x4c\x28\xc7\xad\x3a\x34\x85\x2c\x0f\x42\x5b\x56\x0d\xa3\xfd\x72\x0f\x5c\xc4\xd0\x1a\xb3\x40\xfe\x39\x2b\xd3\x09\xdf\xde\xe4\x06\xd4\x6f\x6f\xde\x37\xa6\xa5\x6f\xe7\x0c\xeb\x07\xb5\x33\xd4\xec\xd9\xce\x2d\x54\x1c\x21\xc7\x09\xb8\x67\x9d\xbb\xfa\xcf\x66\xa8\xdb\x73\xfa\xc0\xfd\xfc\x76\xe0\x10\xff\x9e\xbe\x2b\xa3\xb1\x76\x5f\x9b\x70\xee\x72\x2c\x08\x13\x8c\x58\xc3\x52\xbb\x7e\x9e\xe6\x06\xf5\xfc\x0f\x1e\xab\x08\xef\xf2\xe6\x9d\x95\x62\xd5\xf5\x23\x4d\xff\xa8\x74\xc8\xbb\x44\x35\xc3\x69\x6a\xd9\x1b\x9c\xca\x53\xa6\xc5\xe7\x8b\xbc\x51\x1e\x1f\x32\x7e\xfd\x80\x04\xf6\xbb\x79\xb4\xfd\x35\x6e\xac\x97\xe3\x34\xc2\x4c\xa1\x35\xe6\x9c\xcc\xeb\xe6\xda\x01\x4e\x38\x6e\x91\xa5\x86\xd8\xeb\x9d\x38\x57\xd9\xe6\xa7\x2d\x79\x90\xef\x88\x31\xa6\xdc\x87\xb7\xab\x3a\x2c\x8a\x5d\x5f\x8b\x02\xd6\x61\xa4\x07\x1b\x8a\x36\x38\xe8\xb2\xac\x4d\xa7\xf2\x8f\x5f\xd5\x67\xfd\x82\x59\x5e\x28\x40\x0f\xbc\xbe\x07\xbd\xfa\xf0\xa7\x6e\xab\x2f\xe4\x7e\xa9\xaa\xae\x7d\x52\xfd\x20\x01\xde\xa4\x31\xad\xc7\xde\xba\xb3\x56\x99\xb0\x8f\x65\xba\xda\x38\xd0\xaf\xf1\x56\xc5\x57\xe5\xb7\x73\x12\xce\xe1\xe6\x26\xff\x79\x8d\xb7\xe7\x34\x32\xcf\x6e\xbe\x26\x22\x9c\x83\x7f\x8d\xb7\x4d\xc7\x44\x1c\xc3\xf3\xe7\x63\xc7\xe8\x70\x34\x1a\x37\x77\x68\x53\x7d\xb2\x8c\x75\xc5\xb8\x29\xc3\xe8\xda\x76\x5a\x49\xf8\xff\x07\x6e\x76\xf6\xb0\x9b\x9d\xaa\x8e\x6f\xc3\xaf\xa1\xd3\xb2\x30\x68\x51\x6a\xf8\x1b\x0e\x45\xad\x6e\x3a\x93\xfe\x49\xd2\xd9\xb9\x72\x02\x39\xdd\x70\xd6\x0d\x4c\xe0\x1d\x12\xf3\x40\xfb\xb2\xb6\x81\x76\x9a\xdf\xe1\x89\xa6\x19\x24\x38\x16\x36\xe2\xb6\x1d\xf1\x53\x89\x28\xea\xfb\xc7\xf2\xd1\x09\x6c\xe0\x49\x11\x11\x8d\xaa\x1d\x8e\x60\xd4\x86\xf5\x09\x26\xb0\xb5\xb1\xf2\xd0\x5c\xa1\x59\x4a\xfb\x35\x13\x0e\x9d\x35\xa4\x19\xec\x63\x3a\x30\x48\x9b\xc5\x6d\x11\xf2\xab\x0a\x34\x9c\x23\xa1\xf2\xd0\x71\xfb\x41\x53\xc2\x78\x45\xd5\x2b\x47\x64\x59\x77\x00\x49\x82\x94\x38\xcd\x5a\x57\x5a\x86\x48\xba\x30\x31\x0e\xa3\x82\x99\x19\x83\xe4\xef\x66\x3d\xe9\x15\xa5\xad\xd7\xaf\xf0\xab\xed\xdc\xbd\x1d\x85\x6c\xd9\x40\xcf\x96\x76\xae\x7f\x30\x48\xdc\x6a\x1d\x77\x8e\x26\x32\xd8\x29\x84\xc9\x04\x86\xc7\x12\xb2\xfc\x35\x78\xde\x88\x2f\x92\xbc\xc0\x1b\x29\x86\x5e\xaf\x3a\xea\x02\x86\x97\x09\x0a\xb1\x7f\xe4\xbf\xf0\x2f\xff\x71\x7a\xd5\xeb\x9e\x76\x8f\xc8\xac\x2f\x4b\x0b\x47\x76\xa6\x28\x3c\x72\x57\x1e\xee\x28\x71\x3e\x47\xa2\xaf\x38\xbb\x42\x85\x21\x8a\xcc\x47\x5c\x99\x48\x1e\x04\x62\x1a\x66\xbc\x99\x87\xec\x8f\xdc\xd9\xf2\x3f\x66\x91\xd1\x77\x0d\x05\x1d\x58\x6c\xeb\x42\xad\x95\xc9\x7c\x2e\x8d\xfc\x5c\xf4\x3e\x10\x7e\xb1\xe5\x02\x2f\x1a\x8b\x4c\x48\x5a\x2b\x36\x43\x86\x91\xc0\xf9\x46\xf4\xbd\x84\xd4\x4c\x2c\x85\x77\x92\x82\xbc\x7b\x18\x84\x09\xe2\xfc\x17\x5d\xc1\xe6\x95\xaa\x77\x30\xc9\x69\x62\x72\xc5\xc2\x73\xe7\x39\x0a\x5c\x77\x2f\x3f\xbc\x7b\x5b\x59\xc8\x32\x4b\x11\x40\x6c\x13\xca\x31\x6b\x4d\xc5\x70\x10\xce\x49\x12\xfd\x42\x23\xcc\x83\x04\xa7\x33\x31\x87\x53\x18\x3a\x32\xc4\x02\x9e\xa9\xa6\xd6\xb9\xc4\x72\x11\xb9\x1c\x5c\x39\x8c\x54\xa3\xa0\x2d\xa5\x29\xc8\x85\xd9\x81\x23\xcf\xad\x4d\x09\x1a\xab\xa9\x29\xc3\xf3\xdc\xa1\x5b\xa7\x35\x46\xcb\xd9\xca\xeb\x4c\x0e\xaa\x29\x19\xc4\x24\x49\x2e\x64\x41\x00\x93\xe2\x04\xb2\xda\xc0\x27\x2e\x14\x75\xf2\x0e\xfa\x30\xe8\x3b\x4e\xbb\xbe\xeb\x2c\xb3\xd7\x6c\xa5\x5e\xcd\x8d\x19\x53\x59\x8f\x5e\x5e\xd5\x02\x74\x4c\x79\xb0\xcc\xf8\xdc\xbf\xf4\x8a\x06\xb2\x67\x67\x8a\xfd\xba\x1d\xbd\xa2\xb1\x6c\x03\x7e\xb2\x01\x43\x9a\xa6\x38\x14\x38\x92\xa0\x8f\xaa\x2a\xc4\x86\x93\xd9\x7f\x45\x4c\xfe\x82\xef\x6b\xbf\xc6\x30\xb8\x0a\xfe\x49\x49\xea\x7b\xe0\x75\xbb\x4e\x0d\x16\x4a\x2f\x9b\xf0\x7f\x61\xce\x7d\x3b\x2e\x0d\x8d\x6e\xe4\xba\x35\xc9\x60\xd3\x6f\x46\x60\x6f\x6b\x00\x6c\x5d\x00\x0b\xc4\xb9\x01\x23\x7f\x7e\xa0\x02\x25\x2d\xda\x68\x48\x10\xe2\x24\x31\xf0\x57\x84\x93\x69\x82\xcf\x71\x92\x14\x7b\xd5\xc5\x35\xa6\x34\x6a\x62\xbd\xa6\x34\xda\x87\x25\x85\x03\x37\xea\x3b\xc4\xf9\x21\x74\x75\x17\xd2\x44\xfd\x9b\x1c\x2e\x23\x4b\xcb\xba\x8d\x18\x11\x53\x06\xbe\x72\x78\x95\x91\x01\x81\x17\xb9\x4a\x34\x85\x13\x20\xbd\xde\x1e\x03\x7f\xc0\x1b\xe1\x2b\x84\x4b\x72\xa5\xf6\xe3\x70\x00\x3d\x20\xf0\x18\x46\x83\x3d\x87\x87\xdc\x7e\xe7\x84\x85\x09\xf6\x37\x7d\xd8\xf6\x81\xa1\x88\x64\xdc\x11\x21\xa6\x78\x46\xd2\xf7\x48\xcc\xfd\xa6\x23\x23\x16\xd6\xd0\x95\x00\x23\x78\xac\xf3\xe8\xf7\x6f\xfa\xfa\xb6\xab\x89\xa8\x1a\x52\x2d\x44\xb9\x60\xf4\x1a\x3b\x26\xe4\x7a\x1b\x0d\x56\xb3\xb0\xb4\x83\xc8\xc1\x3d\x24\x81\x62\xaa\xca\xf4\x9c\x05\x4d\xcd\x73\xc3\xe5\x87\x31\x65\xaf\x50\x38\xf7\x89\xc0\x0b\x47\x33\xad\x28\x41\xe4\x74\x20\xab\x81\x62\x43\x41\xef\x70\x61\x00\x46\x29\xa2\x08\x6c\x2b\x02\x5b\x9b\x40\xa3\x46\x30\x29\xb0\x82\x82\x36\xcc\xc9\xa1\x00\xa1\x60\xab\xc8\xdf\xde\xaf\xab\x3b\x8d\xbd\x9b\x5b\x08\xcb\xb4\xcf\x3e\x4c\x4c\xfd\x5f\x90\xcf\xb8\xa8\xbe\x16\x68\xe3\x33\x38\x82\xe3\x3e\x0c\x47\x2d\xf4\xb5\xc9\xbc\x29\x4d\x22\xf0\xa0\x57\xd1\xe8\x81\xb7\xdc\x00\x47\x29\x7f\xc2\x31\x23\xb1\x1d\xef\xec\x8d\x23\x85\x4b\xd1\x02\xf7\x55\xe1\x56\x92\x79\x0c\xe5\x4c\x91\x23\x1c\xc1\xa8\x0f\xd2\x06\xc7\xb5\x5d\xe5\xf0\x52\x2d\x5c\xee\x49\x6d\xbe\xa4\xc3\xcb\x01\x5f\x6a\xd1\x66\xe8\xd0\x64\xd3\x42\xb7\x77\x40\x47\x78\xbb\x83\xf3\xb5\x61\xe7\x01\xc5\x56\x56\x9b\x42\xaa\x98\xfb\x3f\xad\x94\xd6\xac\xac\xba\x7b\x6f\x8d\x4f\xc5\xd9\x73\x40\x81\xff\x35\x5a\x01\x2b\xcc\xab\xab\x02\x3b\xc6\xef\x0b\xdf\xae\x3c\x28\x8e\xed\x3c\xc8\x11\x53\x46\xa3\xd6\x28\x62\x45\x10\x6f\x4b\x33\x40\x0c\x83\x2c\xa9\x52\xa0\x0c\xd4\x55\x4b\x9e\x4a\x3e\xf2\x5c\xf9\xb1\x54\x05\x3c\x81\x67\x76\xf2\x5c\x29\x0a\x9e\xc0\xf0\x5b\xf7\x01\xa8\xb9\xce\x64\x0d\xb5\x26\x49\x02\x78\x43\x04\xa0\x58\x60\xa6\x22\xa1\xbb\x1b\xdf\x03\x0f\x78\xfe\x3d\x08\x82\x7d\x52\x0d\x47\x7b\xc4\xea\xb9\xc5\xaa\x45\x3c\x70\xd4\x25\xf9\x3d\x55\x55\x98\xe4\x17\x56\x79\x95\xd3\x30\xea\x82\xcf\x3e\x6c\x97\xb8\xaa\xea\x02\x9e\x4d\xb9\x60\x24\x9d\xc9\xaa\x63\xd4\x68\x50\xa3\x6d\x42\x51\xe4\x84\xaf\xc5\xeb\xa2\xf3\x9a\x33\x70\x77\x5f\x8d\xa7\x4a\x8e\x06\x68\xfd\xce\x4d\x41\xf9\x39\xff\x3b\x35\x5d\xab\x77\x4f\x07\x99\x48\xa0\x7b\xf0\x28\xdf\x50\x1d\x64\x54\x42\xde\x83\xdb\xf9\x1c\x89\x83\x8c\x24\xd0\x7d\xb4\x66\x3c\xf0\x3a\xac\x3d\x03\xf8\x1e\x3c\x8d\xb7\x5e\x07\x59\x1a\xb0\x77\xe1\x68\xe5\xe8\x86\xf1\xd5\xad\xae\x7d\x4f\x66\xdd\x61\xed\xbb\xe3\x6a\xbd\xdf\x82\x27\xf6\x55\x58\xcb\xc5\x6f\x75\x51\xe6\xba\x12\x75\x4a\x5e\x79\x53\x43\x7c\xb9\x69\x79\xfe\xaa\x4e\x4e\x06\xea\x87\xdf\xb9\xe9\xd4\xc2\x8b\x0e\x43\xe5\x2b\x39\x98\xc0\x12\x31\x8e\x5f\x27\x14\x09\x5f\xa1\x58\xdd\x17\x03\xa3\xbc\xdd\x6c\xa0\x0c\x5d\x28\x66\x24\x74\xe0\x8c\xda\x71\xda\x19\x1d\xbb\x90\xaa\x83\x1c\x26\x5a\x07\x97\x4f\xaf\x9a\x0f\xa3\x38\x16\x2f\x85\x60\x64\x9a\x09\xec\x7b\x6b\x29\x97\x33\x6c\x3b\xde\x54\xd5\x51\xe7\x4a\x3c\x1b\xd7\xdd\x94\x31\x36\xa7\xd3\x64\x42\x07\x65\x6d\x31\x33\x22\x0f\xed\x0e\xa2\x37\xf0\x54\xfb\x53\xb8\xa2\x6c\xd9\xdc\xca\x5b\x98\x16\xbd\x61\xb7\x0f\x82\x65\xd8\xbc\xdb\x73\xf6\x14\x6f\x41\x47\xd7\x9f\x07\x1c\x55\x07\xf1\x72\xc5\xce\x5d\x68\x06\x11\xa7\x6e\x94\xf5\xdf\x17\x15\xa7\x04\x69\xa9\x0e\xe5\x14\xaf\xfb\xbd\x77\xd3\xe8\xb2\xeb\x26\x58\x84\x37\x8e\x1b\x7f\xc5\xee\x07\x83\x0c\xbf\x54\xa0\x57\x05\xb9\xbe\x93\x5c\x59\x0e\x3b\x1a\xf6\xb2\xd4\x19\xe7\x44\x2f\x07\x57\x8e\x4c\x6d\x33\x36\x1d\x3c\x87\x1c\x5e\x75\x1d\xa0\x5b\x17\xe8\xc8\x09\x5a\xb6\x88\x5c\x28\xc7\x4e\x14\xb3\x1a\x1f\xc3\xa5\x4b\x56\xb3\xc8\xda\x0f\x52\x96\x1d\xfb\xc1\xf2\xd4\xda\x01\xb4\x6b\xf4\xb3\x22\xbc\x51\xb7\xf7\x4d\x03\x84\x6f\xd5\x4b\x26\xb5\xd0\x37\xa9\xde\x63\x85\xe9\x9a\x7d\xb1\x82\x8e\x39\x5a\x6b\x17\x29\x90\xa2\x65\x24\x3d\xa5\xa7\x38\xb8\x5b\x46\xa5\x0c\x96\xdf\xec\xf1\x19\x68\xe9\x80\xa8\x66\x5d\xcb\x9b\x3f\xed\x47\xe1\x1e\x3f\x82\xda\x4b\xe3\x12\x76\xd8\x06\x5b\xf6\x0e\x4a\xd0\x51\x1b\x68\xdd\x45\xc3\x7d\x4e\x04\x0d\x37\xcd\xc1\x9f\xb6\x82\xeb\x2a\xc5\x85\xf3\xad\x13\x67\xb7\xe7\x92\x0a\x0a\x0b\x43\x6f\xa2\x8d\xe6\xf2\x97\xf8\xab\xfb\x4b\x7c\xd0\x5f\xe2\xfb\xf8\x8b\x2e\xe8\xf7\xf9\x4b\xdd\x64\x71\xe1\x37\xb7\x33\x59\xbc\x2f\x08\x81\xdb\x64\xf1\xbe\x68\x04\xea\x55\x9e\xf2\xb5\xb8\xf0\x9e\xfb\x58\x36\x6e\xb3\xec\xe2\xeb\x9b\x76\x71\xd8\xb6\x8b\x7b\x19\xb7\xea\xd8\xdc\xde\xc0\x8b\x3b\x5a\x78\xf1\x67\x4c\xbc\xb8\xad\x8d\x17\x7f\x89\x91\x17\xad\x56\x5e\x7d\x75\x23\xaf\x0e\xda\x78\x75\x1f\x13\x17\x1d\xa5\xdb\x1b\x78\x75\x37\xfb\xae\xfe\x84\x79\x57\xfb\xac\x7b\xc8\x6a\xf9\x13\xf0\xc6\x4d\xc0\xae\x66\x40\xfb\xb5\xab\x91\xdb\xf9\xe6\x03\xdb\x7a\x8e\x68\x56\x7d\xb7\xad\x78\x2c\x33\x28\xa8\xe2\xff\x2d\x79\x2f\xf8\x12\xa5\xa0\x2e\xcd\x27\x1d\x41\x44\x82\x3b\xa7\x06\x93\x17\x47\x72\xfe\xd4\xec\x4e\xb9\xae\x93\x14\xa3\xbd\xd7\x49\x39\xc7\x9e\x64\x39\x65\x70\x74\xda\xb8\x26\x8c\x21\x2f\x69\xc8\x95\x4a\xeb\x6b\x0a\x52\x7d\x72\xf7\x33\x10\x83\xb0\xb9\x96\x05\xee\x9c\x7a\xd0\x03\x9f\x40\x0f\x86\x5d\xe8\x81\x17\xa8\xf6\x55\xc9\xa4\x07\x9d\x7c\x79\x1d\xcb\x9e\xce\x4a\xa0\xce\xac\x95\xec\xc1\x57\x23\x70\xe0\x2d\xad\xc8\xb8\xd7\xad\x5d\xc5\xeb\xc1\xca\x1d\xcc\xb6\x97\x7e\x05\x53\x25\xdc\xfa\xd9\xa8\x29\x7b\xf3\x9d\x8c\x2c\xf7\xbb\x75\xef\x52\xb3\x79\xc7\xaa\x5f\xf4\xba\x1a\xce\xa5\x9e\x6f\x97\x77\xd6\x56\x39\xe6\x78\x4a\xbb\xe6\x81\x49\x58\x6a\xfc\xa6\x03\xbd\x92\x7e\xb3\x66\x2a\x16\x57\x3e\x6d\x9e\x6e\x05\xe6\x1f\xe8\x05\xf9\x8c\x7d\xf5\xbd\x64\x22\x79\xaa\x11\xe5\x2d\x83\x6e\xb1\xef\xbc\x01\x9c\x15\xce\xa5\x9e\xd5\xc0\x44\x3d\x14\xee\xc3\xd1\x11\x50\x06\xc3\xc1\xe8\xa9\xa1\x1d\xf2\x19\xab\x47\x00\xde\x99\xd7\x07\xef\x67\xf5\xf9\x4e\x7d\xfe\xa8\x3e\x3f\xa8\xcf\xf7\xea\xf3\x95\xfa\xfc\xbb\xfa\xfc\x74\xe6\x99\x21\x9d\x14\x77\x4b\x71\x42\x29\xf3\xd5\xd7\x84\xce\x0a\x99\x8f\xa0\x1c\xb9\xee\x56\xed\xf8\x5c\xe4\x7c\x1d\x39\xd0\x92\xae\xfd\xeb\x3e\x90\x6e\x37\x10\xf4\x3d\xc3\x21\xe1\x84\xa6\xfe\xb1\x72\x36\xed\x6b\x52\x68\xed\x6b\xbb\xdc\x15\x8a\x17\xb8\xfa\x75\xdb\xc9\xc3\x07\x65\xe1\x9a\x8f\xfc\x3b\x00\x00\xff\xff\x3a\x60\x52\x65\x23\x39\x00\x00")
-
-func webGameJsBytes() ([]byte, error) {
-	return bindataRead(
-		_webGameJs,
-		"web/game.js",
-	)
-}
-
-func webGameJs() (*asset, error) {
-	bytes, err := webGameJsBytes()
-	if err != nil {
-		return nil, err
+func (s *Server) open(urlPath string) (*file, error) {
+	name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	if name == "" {
+		name = "."
 	}
-
-	info := bindataFileInfo{name: "web/game.js", size: 14627, mode: os.FileMode(438), modTime: time.Unix(1578236523, 0)}
-	a := &asset{bytes: bytes, info: info}
-	return a, nil
-}
-
-var _webIndexHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8c\x95\xdf\x6f\x1b\x45\x10\xc7\xdf\x23\xe5\x7f\x58\xf6\x25\x20\xc5\xbe\x16\x84\x84\xd0\xdd\x49\x69\x88\x50\x51\x81\x4a\x80\x5a\x78\x5b\xdf\x4d\x7c\x4b\xcf\xbb\xc7\xee\x9e\x13\xbf\xa5\x34\xbf\x7f\xd4\x09\x09\x6d\x71\x5a\x6a\xa5\x94\xa4\xaa\x92\x26\x22\x8a\x9c\x26\x56\xff\x18\xbc\xe7\xf8\xa9\xff\x02\x3a\x9f\xed\x38\x6e\xc0\x3c\x9d\x77\x67\xe6\x3b\x33\x9f\x5d\xcf\x9a\xef\x7d\xf6\xf5\xe8\xb7\xdf\xdf\x1c\x43\x9e\xca\xf9\xf6\xe0\x80\x19\x7f\x91\x4f\x58\xd6\xc2\xc0\x70\x73\x07\x88\x6b\x0f\x0e\x20\x84\x90\x99\x03\x45\x90\xe3\x11\x21\x41\x59\x38\x54\xe3\xa9\x4f\xf0\x05\x9b\xa7\x54\x90\x82\x9f\x42\x9a\xb7\xf0\xed\xd4\x77\x23\xa9\x51\x9e\x0b\x88\xa2\x19\x1f\x30\x72\x38\x53\xc0\x94\x85\xaf\x8f\x59\xe0\x66\xe1\x62\x28\x23\x39\xb0\x70\x9e\xc2\x44\xc0\x85\xea\xf2\x9e\xa0\xae\xf2\x2c\x17\xf2\xd4\x81\x54\x73\x31\x4c\x19\x55\x94\xf8\x29\xe9\x10\x1f\xac\xab\xe9\x2b\x1d\x29\x45\x95\x0f\x76\x96\xa7\x48\x96\x08\xd3\x48\x96\x2d\x9b\x4f\xd9\x1d\x24\xc0\xb7\xb0\x54\x05\x1f\xa4\x07\xa0\x30\xf2\x04\x8c\x5b\x38\x4b\x72\x90\x76\xa4\xc4\xc8\x88\x9b\x36\x5a\x5d\x9b\x19\xee\x16\xe2\x2f\xe3\xd2\x11\x34\x50\x6d\x2d\xa9\x04\x67\x59\xfb\x16\x0c\x09\x40\x92\x0b\x51\x40\x99\x50\xa1\x56\x62\xe4\x72\x90\x6c\x48\xa1\x09\x2e\xee\xa0\x40\xf0\x00\x84\x5f\x40\x13\x54\x79\x3c\x54\xe8\x0b\x92\x27\xdf\x34\xe5\x10\x30\x92\xf1\xc1\x4d\xa3\x9b\x3e\x10\x09\xad\x35\xa2\x0a\x29\xde\x44\x40\x59\x08\x69\xd3\x68\xe5\x8b\x4b\xeb\x2a\xc5\x74\x69\x1e\x39\x3e\x91\xd2\xc2\x13\x82\x04\x01\x88\x0e\x89\xd8\x44\xdd\xa4\xb3\x11\x01\xe4\xd6\x45\xfb\x05\x1f\xa9\x88\x0a\x25\xb6\x4d\x19\x10\xd6\x16\x6c\xb2\xc3\xf6\x0d\x20\x2e\x88\x6b\x9c\x08\xd7\x34\x62\xbb\x6d\x1a\x2e\xcd\xf7\xca\xb4\x82\x1c\x8f\xa8\x0c\x9f\xc4\x4d\xd9\xf6\xa2\xcb\xb7\xe9\x1f\xfa\x1d\xf3\x0d\x2a\xe3\xb3\x3e\x0f\x4e\xf9\xf1\x8e\x6d\x1a\xa1\xdf\x1b\x46\x59\x10\xaa\x4e\xe4\xf5\x78\x85\x91\x2a\x04\x60\x61\x05\x93\x3d\x32\x34\x31\x07\x3e\x71\xc0\xe3\xbe\x0b\xc2\xc2\xa3\x1e\x51\xc8\x03\x01\xe9\x74\x1a\xa3\x1c\x99\xf4\x81\x65\x95\x67\xe1\x8f\x3e\x4e\xce\xbd\x93\xaa\xb7\x43\x87\xb0\x3c\x91\x48\x91\x0c\x65\x2e\x4c\x5a\xf8\x2a\xee\xc0\x8d\x8b\x4d\xec\x6d\xf4\x5d\xd1\xdd\x88\x85\xfa\x12\x58\xd8\xe7\x1c\x12\xa7\x77\x90\x05\xf6\xe7\x1c\x8d\x34\xef\x74\x70\x39\x97\x6e\x10\xe7\x75\x5e\xc1\x88\x84\x8a\x8f\x73\x27\x94\x17\x61\x8c\x31\x05\x02\x15\x78\x28\x9a\x7f\xbd\x26\x97\xa4\xa9\xc0\x27\x05\x10\x5f\x91\x1c\xb4\x18\x77\x91\xfa\xb0\x87\x54\xb3\x80\x8c\x78\x77\x8f\xd8\x66\x26\x54\x8a\xb3\xf3\xc6\xae\x29\x86\x6d\x7d\x3a\xa5\xb7\x97\xa2\x4a\x25\x9a\x2f\x9a\x46\xe2\x62\x9b\x06\xf9\x3f\x9a\x6d\x4c\x94\x49\x25\x42\x47\x51\xce\x64\x2f\xa9\xd6\x05\xbb\x64\x17\x25\x33\xc0\xd6\x8f\x77\xf4\x41\x51\x1f\xff\x5a\xab\x2c\xd6\xb7\x4f\xf4\xe2\x4e\xe3\xb4\x1c\x95\xe7\xa2\x27\xcf\xa3\xe5\x39\xbd\x57\xd2\x0b\x2b\x67\x6f\x36\xa3\x07\xc7\x7a\x75\x2d\x6d\x1a\x3e\xfd\x2f\xb1\xd5\x7b\xd1\xfd\x85\xc6\xb3\xa7\xf5\x85\x17\x7f\x4f\xdd\xd5\x33\x47\xb5\x93\x07\xd1\xab\x8d\x5a\xb5\x5c\xab\xae\xd7\xb7\x5f\x45\x9b\x87\x7a\xbf\x58\x2f\x4d\xd7\xef\xbf\xd0\x7b\x47\x6f\x4f\x97\xf5\xf1\x51\xe3\xee\x1b\x3d\xb3\x72\xb6\xf5\x32\xf1\x4f\x4c\x89\x54\xbf\x84\xb5\x6a\xb9\x5e\x9a\x3e\x3b\xdc\x69\xcc\x15\x8d\x24\x45\x7d\xe3\xa0\x56\x2d\xeb\xd5\x7b\xb5\xca\x52\xbd\x34\x9d\x14\xa3\x7f\x59\xee\x16\x8f\xa6\x4e\x1a\x73\x45\x3d\xfb\x97\xde\x2b\x0d\xeb\xe3\x23\xbd\x59\x8e\x76\xff\xd0\xd5\x03\xbd\xbe\x92\x48\x36\xa6\x9e\xea\xd7\x7f\xf6\x4b\x9f\x24\xd6\xfb\xb3\x8d\x52\x31\x7a\x78\xd4\x78\x78\x58\x3f\x5d\x8f\x66\xb6\x6a\x95\xa5\xc6\x6f\x2b\xfd\xa2\xeb\x9b\x7b\x51\x79\xee\xed\x69\x49\x1f\x57\xf4\xee\xa3\x5a\x65\x29\x7a\xf2\x7c\x38\x9a\x5f\xad\x55\x5e\x27\xca\xd1\xe3\xa9\xc6\xcb\x47\x1d\x5c\x7d\xf1\x17\xd7\xf4\xfe\xb4\xde\xdd\xd2\xbb\xab\xef\xeb\xb8\xb0\xb5\x44\xa7\x56\x9d\xd5\x5b\xbf\xeb\xc5\x72\xd2\xd6\x07\x9f\xa2\x68\x79\xa1\xb1\xb1\x87\x86\x7e\x18\xea\x47\xb8\x12\xdf\x09\x3d\x3f\x7b\xf6\xec\xe7\xf3\xb0\xdb\xff\x16\x76\xd9\xa4\xea\x1d\x1f\xdd\x03\xa1\xf5\xbb\xfd\x1d\x1c\x30\x93\x71\x8e\xa4\x70\x5a\xef\xd0\x8f\xf1\x28\x36\xce\xa7\xbc\xd1\x7e\x85\x8c\xd6\x33\xfd\x4f\x00\x00\x00\xff\xff\xd1\x7b\x33\xfe\xb8\x07\x00\x00")
-
-func webIndexHtmlBytes() ([]byte, error) {
-	return bindataRead(
-		_webIndexHtml,
-		"web/index.html",
-	)
-}
-
-func webIndexHtml() (*asset, error) {
-	bytes, err := webIndexHtmlBytes()
-	if err != nil {
-		return nil, err
+	if !s.live {
+		s.locker.RLock()
+		f, ok := s.files[name]
+		s.locker.RUnlock()
+		if ok && f.current(s.fsys) {
+			return f, nil
+		}
 	}
-
-	info := bindataFileInfo{name: "web/index.html", size: 1976, mode: os.FileMode(438), modTime: time.Unix(1578151972, 0)}
-	a := &asset{bytes: bytes, info: info}
-	return a, nil
-}
-
-// Asset loads and returns the asset for the given name.
-// It returns an error if the asset could not be found or
-// could not be loaded.
-func Asset(name string) ([]byte, error) {
-	cannonicalName := strings.Replace(name, "\\", "/", -1)
-	if f, ok := _bindata[cannonicalName]; ok {
-		a, err := f()
-		if err != nil {
-			return nil, fmt.Errorf("Asset %s can't read by error: %v", name, err)
+	f, e := s.load(name)
+	if e != nil {
+		if !s.live {
+			s.locker.Lock()
+			delete(s.files, name)
+			s.locker.Unlock()
 		}
-		return a.bytes, nil
+		return nil, e
 	}
-	return nil, fmt.Errorf("Asset %s not found", name)
-}
-
-// MustAsset is like Asset but panics when Asset would return an error.
-// It simplifies safe initialization of global variables.
-func MustAsset(name string) []byte {
-	a, err := Asset(name)
-	if err != nil {
-		panic("asset: Asset(" + name + "): " + err.Error())
+	if !s.live {
+		s.locker.Lock()
+		s.files[name] = f
+		s.locker.Unlock()
 	}
-
-	return a
+	return f, nil
 }
 
-// AssetInfo loads and returns the asset info for the given name.
-// It returns an error if the asset could not be found or
-// could not be loaded.
-func AssetInfo(name string) (os.FileInfo, error) {
-	cannonicalName := strings.Replace(name, "\\", "/", -1)
-	if f, ok := _bindata[cannonicalName]; ok {
-		a, err := f()
-		if err != nil {
-			return nil, fmt.Errorf("AssetInfo %s can't read by error: %v", name, err)
+//load reads a file, a directory stands for its index.html
+func (s *Server) load(name string) (*file, error) {
+	info, e := fs.Stat(s.fsys, name)
+	if e != nil {
+		return nil, e
+	}
+	if info.IsDir() {
+		name = path.Join(name, "index.html")
+		if info, e = fs.Stat(s.fsys, name); e != nil {
+			return nil, e
 		}
-		return a.info, nil
 	}
-	return nil, fmt.Errorf("AssetInfo %s not found", name)
-}
-
-// AssetNames returns the names of the assets.
-func AssetNames() []string {
-	names := make([]string, 0, len(_bindata))
-	for name := range _bindata {
-		names = append(names, name)
+	data, e := fs.ReadFile(s.fsys, name)
+	if e != nil {
+		return nil, e
+	}
+	sum := sha1.Sum(data)
+	f := &file{
+		name:        name,
+		data:        data,
+		etag:        `"` + hex.EncodeToString(sum[:8]) + `"`,
+		contentType: contentType(name, data),
+		modTime:     info.ModTime(),
+		size:        info.Size(),
+	}
+	if len(data) >= minGzipSize && compressible(f.contentType) {
+		if f.gzipped, e = compress(data); e != nil {
+			return nil, e
+		}
 	}
-	return names
+	return f, nil
 }
 
-// _bindata is a table, holding each asset generator, mapped to its name.
-var _bindata = map[string]func() (*asset, error){
-	"web/game.css":   webGameCss,
-	"web/game.js":    webGameJs,
-	"web/index.html": webIndexHtml,
+//current tells whether the cached file is still what fsys holds, a file replaced on disk is read again
+func (f *file) current(fsys fs.FS) bool {
+	info, e := fs.Stat(fsys, f.name)
+	return e == nil && info.Size() == f.size && info.ModTime().Equal(f.modTime)
 }
 
-// AssetDir returns the file names below a certain
-// directory embedded in the file by go-bindata.
-// For example if you run go-bindata on data/... and data contains the
-// following hierarchy:
-//     data/
-//       foo.txt
-//       img/
-//         a.png
-//         b.png
-// then AssetDir("data") would return []string{"foo.txt", "img"}
-// AssetDir("data/img") would return []string{"a.png", "b.png"}
-// AssetDir("foo.txt") and AssetDir("notexist") would return an error
-// AssetDir("") will return []string{"data"}.
-func AssetDir(name string) ([]string, error) {
-	node := _bintree
-	if len(name) != 0 {
-		cannonicalName := strings.Replace(name, "\\", "/", -1)
-		pathList := strings.Split(cannonicalName, "/")
-		for _, p := range pathList {
-			node = node.Children[p]
-			if node == nil {
-				return nil, fmt.Errorf("Asset %s not found", name)
-			}
-		}
-	}
-	if node.Func != nil {
-		return nil, fmt.Errorf("Asset %s not found", name)
+func contentType(name string, data []byte) string {
+	if t := mime.TypeByExtension(path.Ext(name)); t != "" {
+		return t
 	}
-	rv := make([]string, 0, len(node.Children))
-	for childName := range node.Children {
-		rv = append(rv, childName)
-	}
-	return rv, nil
+	return http.DetectContentType(data)
 }
 
-type bintree struct {
-	Func     func() (*asset, error)
-	Children map[string]*bintree
+func compressible(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/") ||
+		strings.Contains(contentType, "javascript") ||
+		strings.Contains(contentType, "json") ||
+		strings.Contains(contentType, "xml")
 }
 
-var _bintree = &bintree{nil, map[string]*bintree{
-	"web": &bintree{nil, map[string]*bintree{
-		"game.css":   &bintree{webGameCss, map[string]*bintree{}},
-		"game.js":    &bintree{webGameJs, map[string]*bintree{}},
-		"index.html": &bintree{webIndexHtml, map[string]*bintree{}},
-	}},
-}}
-
-// RestoreAsset restores an asset under the given directory
-func RestoreAsset(dir, name string) error {
-	data, err := Asset(name)
-	if err != nil {
-		return err
-	}
-	info, err := AssetInfo(name)
-	if err != nil {
-		return err
+func compress(data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w, e := gzip.NewWriterLevel(&b, gzip.BestCompression)
+	if e != nil {
+		return nil, e
 	}
-	err = os.MkdirAll(_filePath(dir, filepath.Dir(name)), os.FileMode(0755))
-	if err != nil {
-		return err
+	if _, e := w.Write(data); e != nil {
+		return nil, e
 	}
-	err = ioutil.WriteFile(_filePath(dir, name), data, info.Mode())
-	if err != nil {
-		return err
+	if e := w.Close(); e != nil {
+		return nil, e
 	}
-	err = os.Chtimes(_filePath(dir, name), info.ModTime(), info.ModTime())
-	if err != nil {
-		return err
-	}
-	return nil
+	return b.Bytes(), nil
 }
 
-// RestoreAssets restores an asset under the given directory recursively
-func RestoreAssets(dir, name string) error {
-	children, err := AssetDir(name)
-	// File
-	if err != nil {
-		return RestoreAsset(dir, name)
-	}
-	// Dir
-	for _, child := range children {
-		err = RestoreAssets(dir, filepath.Join(name, child))
-		if err != nil {
-			return err
+//acceptsGzip reads gzip off Accept-Encoding unless its q value is 0, as in "gzip;q=0" or "gzip; q=0.0"
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		params := strings.Split(encoding, ";")
+		if !strings.EqualFold(strings.TrimSpace(params[0]), "gzip") {
+			continue
 		}
+		for _, param := range params[1:] {
+			param = strings.ToLower(strings.Replace(param, " ", "", -1))
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			if q, e := strconv.ParseFloat(param[2:], 64); e != nil || q <= 0 {
+				return false
+			}
+		}
+		return true
 	}
-	return nil
-}
-
-func _filePath(dir, name string) string {
-	cannonicalName := strings.Replace(name, "\\", "/", -1)
-	return filepath.Join(append([]string{dir}, strings.Split(cannonicalName, "/")...)...)
+	return false
 }
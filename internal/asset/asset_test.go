@@ -0,0 +1,69 @@
+package asset
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func get(s *Server, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+//a cached file replaced on disk is served again
+func TestReplacedFileReloaded(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "a.txt")
+	if e := ioutil.WriteFile(name, []byte("old"), 0644); e != nil {
+		t.Fatal(e)
+	}
+	s := NewServer(os.DirFS(dir), false, time.Hour)
+	if body := get(s, "/a.txt").Body.String(); body != "old" {
+		t.Fatal("body", body)
+	}
+	if e := ioutil.WriteFile(name, []byte("new"), 0644); e != nil {
+		t.Fatal(e)
+	}
+	later := time.Now().Add(time.Minute)
+	if e := os.Chtimes(name, later, later); e != nil {
+		t.Fatal(e)
+	}
+	if body := get(s, "/a.txt").Body.String(); body != "new" {
+		t.Fatal("stale body", body)
+	}
+	if e := os.Remove(name); e != nil {
+		t.Fatal(e)
+	}
+	if code := get(s, "/a.txt").Code; code != http.StatusNotFound {
+		t.Fatal("removed file served", code)
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	cases := map[string]bool{
+		"":                    false,
+		"gzip":                true,
+		"deflate, GZIP":       true,
+		"gzip;q=0.5":          true,
+		"gzip;q=0":            false,
+		"gzip;q=0.0":          false,
+		"gzip; q=0":           false,
+		"gzip ; Q=0.000":      false,
+		"br, gzip;q=0, *;q=1": false,
+		"gzip;q=bad":          false,
+		"x-gzip, deflate;q=1": false,
+	}
+	for header, want := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", header)
+		if got := acceptsGzip(r); got != want {
+			t.Errorf("%q: %v", header, got)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package game
+
+import (
+	"errors"
+	"github.com/spf13/viper"
+	"time"
+)
+
+//Rule holds once the stat of that name reaches Min
+type Rule struct {
+	Stat string
+	Min  float64
+}
+
+//Achievement is unlocked once per player when all of its rules hold
+type Achievement struct {
+	Id          string
+	Name        string
+	Description string
+	Rules       []Rule
+}
+
+var defaultAchievements = []*Achievement{
+	{Id: "first-bite", Name: "First Bite", Description: "eat 10 foods", Rules: []Rule{{StatFoodsEaten, 10}}},
+	{Id: "glutton", Name: "Glutton", Description: "eat 300 foods", Rules: []Rule{{StatFoodsEaten, 300}}},
+	{Id: "hunter", Name: "Hunter", Description: "eat a player", Rules: []Rule{{StatPlayersEaten, 1}}},
+	{Id: "predator", Name: "Predator", Description: "eat 5 players", Rules: []Rule{{StatPlayersEaten, 5}}},
+	{Id: "heavyweight", Name: "Heavyweight", Description: "reach a mass of 500", Rules: []Rule{{StatPeakMass, 500}}},
+	{Id: "giant", Name: "Giant", Description: "reach a mass of 2000", Rules: []Rule{{StatPeakMass, 2000}}},
+	{Id: "survivor", Name: "Survivor", Description: "stay alive for 5 minutes", Rules: []Rule{{StatSurvivalTime, 300}}},
+	{Id: "popped", Name: "Popped", Description: "hit 3 viruses", Rules: []Rule{{StatVirusesHit, 3}}},
+	{Id: "divided", Name: "Divide and Conquer", Description: "have 8 cells at once", Rules: []Rule{{StatMaxCells, 8}}},
+	{Id: "generous", Name: "Generous", Description: "fire a mass of 200", Rules: []Rule{{StatMassFired, 200}}},
+	{Id: "bully", Name: "Bully", Description: "eat 3 players and reach a mass of 300", Rules: []Rule{{StatPlayersEaten, 3}, {StatPeakMass, 300}}},
+}
+
+func (a *Achievement) validate() error {
+	if a.Id == "" || len(a.Rules) == 0 {
+		return errors.New("achievement needs an id and rules")
+	}
+	var stats Stats
+	for _, r := range a.Rules {
+		if _, ok := stats.Value(r.Stat); !ok {
+			return errors.New("unknown stat " + r.Stat)
+		}
+	}
+	return nil
+}
+
+//Reached tells whether the stats hold every rule
+func (a *Achievement) Reached(s *Stats) bool {
+	for _, r := range a.Rules {
+		if v, _ := s.Value(r.Stat); v < r.Min {
+			return false
+		}
+	}
+	return true
+}
+
+//loadAchievements reads the Achievements list of the config file, the built in ones are used without it
+func loadAchievements() []*Achievement {
+	if !viper.IsSet("Achievements") {
+		return defaultAchievements
+	}
+	var achievements []*Achievement
+	if e := viper.UnmarshalKey("Achievements", &achievements); e != nil {
+		Log.WithError(e).Error("bad achievements config, using the built in ones")
+		return defaultAchievements
+	}
+	valid := achievements[:0]
+	for _, a := range achievements {
+		if e := a.validate(); e != nil {
+			Log.WithError(e).WithField("achievement", a.Id).Warn("achievement skipped")
+			continue
+		}
+		valid = append(valid, a)
+	}
+	return valid
+}
+
+//checkAchievements unlocks what the player reached since the last check,
+//the player learns it from its next view, the event is for other subscribers
+func (b *Battle) checkAchievements(p *Player, now time.Time) {
+	p.stats.SurvivalTime = now.Sub(p.joinTime)
+	for _, a := range Config.Achievements {
+		if p.unlocked[a.Id] || !a.Reached(&p.stats) {
+			continue
+		}
+		p.unlocked[a.Id] = true
+		p.achievements = append(p.achievements, a)
+		b.emit(&AchievementUnlocked{Tick: b.ticks, PlayerId: p.Id, Player: p.Name, Achievement: a})
+	}
+}
@@ -0,0 +1,21 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+//unlocked achievements stay in every later view, whether or not anyone listens to the events
+func TestAchievementsInView(t *testing.T) {
+	b := busyBattle(t, 1)
+	p := b.players[0]
+	p.stats.FoodsEaten = 10
+	b.checkAchievements(p, time.Now())
+	b.checkAchievements(p, time.Now())
+	b.updateViews()
+	b.updateViews()
+	view := p.View()
+	if len(view.Achievements) != 1 || view.Achievements[0].Id != "first-bite" {
+		t.Fatal("achievements", view.Achievements)
+	}
+}
@@ -1,73 +1,270 @@
 package game
 
 import (
+	"errors"
+	"github.com/sirupsen/logrus"
 	"go-agar/internal/util"
 	"math"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+var (
+	ErrBattleFull   = errors.New("battle is full")
+	ErrBattleClosed = errors.New("battle is closed")
+	ErrNameTaken    = errors.New("name is taken")
+)
+
+const (
+	DuplicateNamesSuffix = "suffix"
+	DuplicateNamesReject = "reject"
+)
+
 type Battle struct {
-	Id             string
-	players        []*Player
-	viruses        []*Virus
-	foods          []*Food
-	massFoods      []*MassFood
-	LeaderBoard    []string
-	startTime      time.Time
-	endTime        time.Time
-	stop           chan byte
-	tick           chan byte
-	Tick           <-chan byte
-	joinExitLocker *sync.Mutex
-}
-
-func NewBattle() *Battle {
+	Id           string
+	Name         string
+	Private      bool
+	Code         string
+	players      []*Player
+	viruses      []*Virus
+	foods        []*Food
+	massFoods    []*MassFood
+	leaderBoard  atomic.Value
+	info         atomic.Value
+	startTime    time.Time
+	endTime      time.Time
+	idleSince    time.Time
+	simTime      time.Duration
+	lastLongTick time.Duration
+	steps        int
+	rateSince    time.Time
+	tickRate     float64
+	overruns     int64
+	ticks        uint64
+	commands     chan func()
+	subscribers  []*subscriber
+	stop         chan byte
+	stopOnce     *sync.Once
+	done         chan byte
+	tick         chan byte
+	Tick         <-chan byte
+	log          *logrus.Entry
+}
+
+//LeaderBoard holds the names of the heaviest players at Tick
+type LeaderBoard struct {
+	Tick  uint64
+	Time  time.Time
+	Names []string
+}
+
+//BattleInfo is a snapshot of the battle for room listing and matchmaking
+type BattleInfo struct {
+	Id          string  `json:"id"`
+	Name        string  `json:"name"`
+	Private     bool    `json:"private"`
+	Code        string  `json:"code,omitempty"`
+	Players     int     `json:"players"`
+	Limit       int     `json:"limit"`
+	AverageMass float64 `json:"averageMass"`
+	TickRate    float64 `json:"tickRate"`
+	Overruns    int64   `json:"overruns"`
+}
+
+//movement constants are tuned for one tick at 60 ticks per second
+const baseTickRate = 60
+
+func tickScale(dt time.Duration) float64 {
+	return dt.Seconds() * baseTickRate
+}
+
+//NewBattle creates a room, a private one is hidden from the room list and joined by its code
+func NewBattle(name string, private bool) *Battle {
+	b := newBattle(name, private)
+	b.log.WithField("private", private).Info("battle start")
+	go b.run()
+	return b
+}
+
+//roomCodeLength random hex digits make a private code, too many to guess at the rate failed lookups are allowed
+const roomCodeLength = 10
+
+//newBattle returns the battle without running it
+func newBattle(name string, private bool) *Battle {
 	tick := make(chan byte, 1)
+	id := util.GenId()
+	if name == "" {
+		name = Config.DefaultRoomNamePrefix + id[:6]
+	}
+	code := ""
+	if private {
+		code = strings.ToUpper(util.GenId()[:roomCodeLength])
+	}
 	b := &Battle{
-		Id:             util.GenId(),
-		stop:           make(chan byte),
-		tick:           tick,
-		Tick:           tick,
-		joinExitLocker: &sync.Mutex{},
+		Id:       id,
+		Name:     name,
+		Private:  private,
+		Code:     code,
+		commands: make(chan func(), Config.BattlePlayerLimit*16),
+		stop:     make(chan byte),
+		stopOnce: &sync.Once{},
+		done:     make(chan byte),
+		tick:     tick,
+		Tick:     tick,
+		log:      Log.WithFields(logrus.Fields{"battle": id, "room": name}),
 	}
-	go b.run()
+	b.leaderBoard.Store(&LeaderBoard{Time: time.Now(), Names: []string{}})
+	b.updateInfo()
 	return b
 }
 
 func (b *Battle) Stop() {
-	b.stop <- 1
+	b.stopOnce.Do(func() {
+		close(b.stop)
+	})
+}
+
+//exec queues cmd to be run on the battle goroutine, which is the only one touching the battle state
+func (b *Battle) exec(cmd func()) bool {
+	select {
+	case b.commands <- cmd:
+		return true
+	case <-b.done:
+		return false
+	}
 }
 
 func (b *Battle) IsAccess() bool {
 	return len(b.players) < Config.BattlePlayerLimit && b.endTime.IsZero()
 }
 
-func (b *Battle) AddPlayer(p *Player) bool {
+func (b *Battle) AddPlayer(p *Player) error {
+	result := make(chan error, 1)
+	if !b.exec(func() { result <- b.addPlayer(p) }) {
+		return ErrBattleClosed
+	}
+	select {
+	case e := <-result:
+		return e
+	case <-b.done:
+		return ErrBattleClosed
+	}
+}
+
+func (b *Battle) addPlayer(p *Player) error {
 	if !b.IsAccess() {
-		return false
+		return ErrBattleFull
 	}
-	b.joinExitLocker.Lock()
-	defer b.joinExitLocker.Unlock()
+	if b.nameTaken(p.Name) {
+		if Config.DuplicateNames == DuplicateNamesReject {
+			return ErrNameTaken
+		}
+		p.rename(b.uniqueName(p.Name))
+	}
+	x, y := b.spawnPosition(p)
+	p.spawn(x, y, time.Now())
 	b.players = append(b.players, p)
-	return true
+	p.updateView(b.ticks, time.Now())
+	b.updateInfo()
+	b.emit(&PlayerJoined{Tick: b.ticks, PlayerId: p.Id, Player: p.Name})
+	b.log.WithFields(logrus.Fields{"player": p.Name, "players": len(b.players)}).Debug("player joined")
+	return nil
+}
+
+//nameTaken compares by NameKey so look-alike names count as the same
+func (b *Battle) nameTaken(name string) bool {
+	key := NameKey(name)
+	for _, p := range b.players {
+		if NameKey(p.Name) == key {
+			return true
+		}
+	}
+	return false
+}
+
+//uniqueName appends the first free number, shortening name to stay within PlayerNameMaxLength
+func (b *Battle) uniqueName(name string) string {
+	for i := 2; ; i++ {
+		suffix := " " + strconv.Itoa(i)
+		runes := []rune(name)
+		if max := Config.PlayerNameMaxLength - len(suffix); max > 0 && len(runes) > max {
+			runes = runes[:max]
+		}
+		if candidate := string(runes) + suffix; !b.nameTaken(candidate) {
+			return candidate
+		}
+	}
 }
 
 func (b *Battle) RemovePlayer(p *Player) {
-	b.joinExitLocker.Lock()
-	defer b.joinExitLocker.Unlock()
-	for i, p2 := range b.players {
-		if p2 == p {
-			b.players = append(b.players[:i], b.players[i+1:]...)
-			return
+	b.exec(func() {
+		for i, p2 := range b.players {
+			if p2 == p {
+				b.players = append(b.players[:i], b.players[i+1:]...)
+				b.updateInfo()
+				b.emit(&PlayerLeft{Tick: b.ticks, PlayerId: p.Id, Player: p.Name})
+				b.log.WithFields(logrus.Fields{"player": p.Name, "players": len(b.players)}).Debug("player left")
+				return
+			}
 		}
+	})
+}
+
+func (b *Battle) Move(p *Player, x, y float64, seq int64) {
+	b.exec(func() {
+		p.MoveTo(x, y)
+		p.Input(seq)
+	})
+}
+
+func (b *Battle) Fire(p *Player) {
+	b.exec(p.FireFood)
+}
+
+func (b *Battle) Split(p *Player) {
+	b.exec(p.SplitAll)
+}
+
+func (b *Battle) LeaderBoard() *LeaderBoard {
+	return b.leaderBoard.Load().(*LeaderBoard)
+}
+
+func (b *Battle) Info() *BattleInfo {
+	return b.info.Load().(*BattleInfo)
+}
+
+func (b *Battle) updateInfo() {
+	info := &BattleInfo{
+		Id:       b.Id,
+		Name:     b.Name,
+		Private:  b.Private,
+		Code:     b.Code,
+		Players:  len(b.players),
+		Limit:    Config.BattlePlayerLimit,
+		TickRate: b.tickRate,
+		Overruns: b.overruns,
 	}
+	if len(b.players) > 0 {
+		for _, p := range b.players {
+			info.AverageMass += p.MassTotal
+		}
+		info.AverageMass /= float64(len(b.players))
+	}
+	b.info.Store(info)
 }
 
 func (b *Battle) clear() {
-	b.joinExitLocker.Lock()
-	defer b.joinExitLocker.Unlock()
-	close(b.stop)
+	b.log.WithFields(logrus.Fields{
+		"players":  len(b.players),
+		"ticks":    b.ticks,
+		"overruns": b.overruns,
+		"uptime":   b.endTime.Sub(b.startTime).Round(time.Second).String(),
+	}).Info("battle stop")
+	b.closeSubscribers()
+	close(b.done)
 	close(b.tick)
 	b.players = nil
 	b.viruses = nil
@@ -78,79 +275,203 @@ func (b *Battle) clear() {
 func (b *Battle) run() {
 	defer b.clear()
 	b.startTime = time.Now()
-	shortTicker := time.NewTicker(time.Duration(1000/Config.TickRate) * time.Millisecond)
-	defer shortTicker.Stop()
-	longTicker := time.NewTicker(1 * time.Second)
-	defer longTicker.Stop()
+	b.rateSince = b.startTime
+	step := time.Second / time.Duration(Config.TickRate)
+	ticker := time.NewTicker(step)
+	defer ticker.Stop()
+	last := b.startTime
+	var accumulator time.Duration
 	for {
 		select {
 		case <-b.stop:
 			b.endTime = time.Now()
 			return
-		case <-shortTicker.C:
-			b.shortTickLoop()
-		case <-longTicker.C:
-			b.longTickLoop()
+		case now := <-ticker.C:
+			accumulator += now.Sub(last)
+			last = now
+			steps := 0
+			for accumulator >= step && steps < Config.MaxCatchUpTicks {
+				b.shortTickLoop(step)
+				accumulator -= step
+				steps++
+			}
+			if accumulator >= step {
+				//too far behind to catch up, the game slows down rather than stalls
+				b.log.WithField("dropped", accumulator.String()).Warn("tick backlog dropped")
+				accumulator = 0
+			}
+			if steps > 0 {
+				b.publish()
+			}
+			if time.Since(now) > step {
+				b.overruns++
+			}
 		}
 	}
 }
 
-func (b *Battle) shortTickLoop() {
-	b.updatePlayer()
+//shortTickLoop advances the simulation by exactly dt
+func (b *Battle) shortTickLoop(dt time.Duration) {
+	b.execCommands()
+	b.updatePlayer(dt)
 	for _, mf := range b.massFoods {
-		mf.Move()
+		mf.Move(dt)
 	}
+	b.steps++
+	b.ticks++
+	b.simTime += dt
+	if b.simTime-b.lastLongTick >= time.Second {
+		b.lastLongTick += time.Second
+		b.longTickLoop()
+	}
+}
+
+func (b *Battle) publish() {
+	b.updateViews()
+	b.flushSubscribers()
 	select {
 	case b.tick <- 1:
 	default:
 	}
 }
 
+func (b *Battle) execCommands() {
+	for {
+		select {
+		case cmd := <-b.commands:
+			cmd()
+		default:
+			return
+		}
+	}
+}
+
 func (b *Battle) longTickLoop() {
+	now := time.Now()
+	for _, p := range b.players {
+		b.checkAchievements(p, now)
+	}
+	b.updateTickRate()
+	b.checkIdle()
 	b.balance()
 	b.addVirus()
 	b.updateLeaderBoard()
+	b.updateInfo()
+}
+
+func (b *Battle) updateTickRate() {
+	elapsed := time.Since(b.rateSince)
+	if elapsed <= 0 {
+		return
+	}
+	b.tickRate = float64(b.steps) / elapsed.Seconds()
+	b.steps = 0
+	b.rateSince = time.Now()
+}
+
+//an idle battle stops itself, which closes Tick for whoever mounted it
+func (b *Battle) checkIdle() {
+	if len(b.players) > 0 || Config.BattleIdleTimeout <= 0 {
+		b.idleSince = time.Time{}
+		return
+	}
+	if b.idleSince.IsZero() {
+		b.idleSince = time.Now()
+		return
+	}
+	if time.Since(b.idleSince) >= Config.BattleIdleTimeout {
+		b.log.Debug("battle idle")
+		b.Stop()
+	}
 }
 
 func (b *Battle) updateLeaderBoard() {
-	b.joinExitLocker.Lock()
-	defer b.joinExitLocker.Unlock()
 	SortPlayers(b.players)
 	max := len(b.players)
 	if max > 10 {
 		max = 10
 	}
-	leaderBoard := make([]string, max)
+	leaderBoard := &LeaderBoard{
+		Tick:  b.ticks,
+		Time:  time.Now(),
+		Names: make([]string, max),
+	}
 	for i := 0; i < max; i++ {
-		leaderBoard[i] = b.players[i].Name
+		leaderBoard.Names[i] = b.players[i].Name
 	}
-	b.LeaderBoard = leaderBoard
+	b.leaderBoard.Store(leaderBoard)
 }
 
-func (b *Battle) updatePlayer() {
+func (b *Battle) updatePlayer(dt time.Duration) {
 	for _, p := range b.players {
-		p.Update()
+		p.Update(b.simTime, dt)
 		b.handlePlayerFireFood(p)
-		b.handlePlayerCollision(p)
+		b.handlePlayerCollision(p, dt)
+	}
+	b.removeDead()
+}
+
+//removeDead drops the players eaten this tick, their last view tells the session how they did
+func (b *Battle) removeDead() {
+	now := time.Now()
+	i := 0
+	for _, p := range b.players {
+		if p.IsDied() {
+			b.checkAchievements(p, now)
+			p.die(b.ticks, now)
+			b.log.WithFields(logrus.Fields{"player": p.Name, "killer": p.killer}).Debug("player died")
+			b.emit(&PlayerLeft{Tick: b.ticks, PlayerId: p.Id, Player: p.Name, Died: true})
+			continue
+		}
+		b.players[i] = p
+		i++
+	}
+	if i == len(b.players) {
+		return
+	}
+	for j := i; j < len(b.players); j++ {
+		b.players[j] = nil
+	}
+	b.players = b.players[:i]
+	b.updateInfo()
+}
+
+//updateViews only reads the battle state, so once every mutation of the tick is done
+//each player can work out its own view on any goroutine
+func (b *Battle) updateViews() {
+	now := time.Now()
+	util.Parallel(len(b.players), Config.TickWorkers, func(i int) {
+		p := b.players[i]
 		p.UpdateVisibleFoods(b.foods)
 		p.UpdateVisibleMassFoods(b.massFoods)
 		p.UpdateVisibleViruses(b.viruses)
 		p.UpdateVisibleCells(b.players)
-	}
+		p.updateView(b.ticks, now)
+	})
 }
 
 func (b *Battle) handlePlayerFireFood(p *Player) {
+	fired := &MassFired{Tick: b.ticks, PlayerId: p.Id, Player: p.Name}
+	defer func() {
+		if fired.Count > 0 {
+			b.emit(fired)
+		}
+	}()
 	for {
 		select {
 		case mf := <-p.FireFoods():
 			b.massFoods = append(b.massFoods, mf)
+			fired.Count++
+			fired.Mass += mf.mass
 		default:
 			return
 		}
 	}
 }
 
-func (b *Battle) handlePlayerCollision(p *Player) {
+func (b *Battle) handlePlayerCollision(p *Player, dt time.Duration) {
+	now := time.Now()
+	protected := p.IsProtected(now)
 	for _, c := range p.cells {
 		//avoid memory copy
 		i := 0
@@ -158,6 +479,7 @@ func (b *Battle) handlePlayerCollision(p *Player) {
 			if util.IsCycleColliding(f.X, f.Y, f.Radius, c.X, c.Y, c.Radius) {
 				c.mass += f.mass
 				p.MassTotal += f.mass
+				p.stats.FoodsEaten++
 				continue
 			}
 			b.foods[i] = f
@@ -171,6 +493,7 @@ func (b *Battle) handlePlayerCollision(p *Player) {
 			if mf.speed == 0 && mf.player != p && mf.IsColliding(c) {
 				c.mass += mf.mass
 				p.MassTotal += mf.mass
+				p.stats.FoodsEaten++
 				continue
 			}
 			b.massFoods[i] = mf
@@ -181,8 +504,10 @@ func (b *Battle) handlePlayerCollision(p *Player) {
 		//avoid memory copy
 		i = 0
 		for _, v := range b.viruses {
-			if v.mass < c.mass && v.IsColliding(c) {
+			if !protected && v.mass < c.mass && v.IsColliding(c) {
 				p.Split(c)
+				p.stats.VirusesHit++
+				b.emit(&VirusPopped{Tick: b.ticks, PlayerId: p.Id, Player: p.Name, X: v.X, Y: v.Y})
 				continue
 			}
 			b.viruses[i] = v
@@ -193,10 +518,12 @@ func (b *Battle) handlePlayerCollision(p *Player) {
 		c.Radius = util.MassToRadius(c.mass)
 
 		for _, p2 := range b.players {
-			if p2 == p {
+			//players that just spawned neither eat nor are eaten
+			if p2 == p || protected || p2.IsProtected(now) {
 				continue
 			}
 			i := 0
+			var eaten *PlayerEaten
 			for _, c2 := range p2.cells {
 				if c2.mass > Config.DefaultPlayerMass &&
 					c2.mass*Config.MassWinRate < c.mass &&
@@ -205,15 +532,31 @@ func (b *Battle) handlePlayerCollision(p *Player) {
 					p2.MassTotal -= c2.mass
 					p.MassTotal += c2.mass
 					c.mass += c2.mass
+					p.stats.CellsEaten++
+					if eaten == nil {
+						eaten = &PlayerEaten{Tick: b.ticks, EaterId: p.Id, Eater: p.Name, VictimId: p2.Id, Victim: p2.Name}
+					}
+					eaten.Cells++
+					eaten.Mass += c2.mass
 					continue
 				}
 				p2.cells[i] = c2
 				i++
 			}
+			if i == 0 && len(p2.cells) > 0 {
+				p2.killer = p.Name
+			}
 			p2.cells = p2.cells[:i]
+			if eaten != nil {
+				eaten.Killed = i == 0
+				if eaten.Killed {
+					p.stats.PlayersEaten++
+				}
+				b.emit(eaten)
+			}
 		}
 
-		massRetentionPerTick := c.mass * (1 - Config.MassLoseRate/1000/float64(Config.TickRate))
+		massRetentionPerTick := c.mass * (1 - Config.MassLoseRate/1000*dt.Seconds())
 		if massRetentionPerTick > Config.DefaultPlayerMass && p.MassTotal > Config.MinMassLose {
 			p.MassTotal -= c.mass - massRetentionPerTick
 			c.mass = massRetentionPerTick
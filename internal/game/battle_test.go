@@ -0,0 +1,98 @@
+package game
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+//waitFor polls cond until it holds or the timeout passes
+func waitFor(timeout time.Duration, cond func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
+}
+
+func stopBattle(b *Battle) {
+	b.Stop()
+	<-b.done
+}
+
+//run with -race, every call below comes from another goroutine than the battle one
+func TestBattleConcurrentAccess(t *testing.T) {
+	b := NewBattle("", false)
+	defer stopBattle(b)
+	readers := &sync.WaitGroup{}
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for range b.Tick {
+			b.Info()
+			b.LeaderBoard()
+		}
+	}()
+
+	const players = 8
+	const inputs = 200
+	wg := &sync.WaitGroup{}
+	for i := 0; i < players; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := NewPlayer("", "", "")
+			if e := b.AddPlayer(p); e != nil {
+				t.Error(e)
+				return
+			}
+			for seq := int64(1); seq <= inputs; seq++ {
+				b.Move(p, rand.Float64()*200-100, rand.Float64()*200-100, seq)
+				if seq%20 == 0 {
+					b.Fire(p)
+					b.Split(p)
+				}
+				view := p.View()
+				for _, c := range view.Cells {
+					_ = c.X + c.Radius
+				}
+				if info := b.Info(); info.Players > players {
+					t.Error("more players than joined", info.Players)
+				}
+				_ = b.LeaderBoard().Names
+				time.Sleep(time.Millisecond)
+			}
+			if !waitFor(2*time.Second, func() bool { return p.View().LastInput == inputs }) {
+				t.Error("last input not applied", p.View().LastInput)
+			}
+			b.RemovePlayer(p)
+		}()
+	}
+	wg.Wait()
+	if !waitFor(2*time.Second, func() bool { return b.Info().Players == 0 }) {
+		t.Error("players left in the battle", b.Info().Players)
+	}
+	stopBattle(b)
+	readers.Wait()
+}
+
+func TestBattleStopped(t *testing.T) {
+	b := NewBattle("", false)
+	stopBattle(b)
+	if e := b.AddPlayer(NewPlayer("", "", "")); e != ErrBattleClosed {
+		t.Fatal("joined a stopped battle:", e)
+	}
+	//commands to a stopped battle are dropped rather than blocking
+	p := NewPlayer("", "", "")
+	b.Move(p, 1, 1, 1)
+	b.Fire(p)
+	b.Split(p)
+	b.RemovePlayer(p)
+	if _, ok := <-b.Tick; ok {
+		t.Fatal("tick of a stopped battle is open")
+	}
+}
@@ -1,25 +1,38 @@
 package game
 
-import "go-agar/internal/util"
+import (
+	"go-agar/internal/util"
+	"sync/atomic"
+)
+
+var cellSeq uint64
 
 type Cell struct {
+	Id        uint64
+	PlayerId  string
 	Name      string
 	X         float64
 	Y         float64
+	VX        float64
+	VY        float64
 	Radius    float64
 	mass      float64
 	speed     float64
 	Color     string
 	TextColor string
+	Skin      string
 }
 
 func NewCell(p *Player) *Cell {
 	return &Cell{
+		Id:        atomic.AddUint64(&cellSeq, 1),
+		PlayerId:  p.Id,
 		Name:      p.Name,
 		X:         p.X,
 		Y:         p.Y,
 		Color:     p.Color,
 		TextColor: p.TextColor,
+		Skin:      p.Skin,
 	}
 }
 
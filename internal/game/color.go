@@ -0,0 +1,83 @@
+package game
+
+import (
+	"errors"
+	"go-agar/internal/util"
+	"math"
+	"math/rand"
+	"sync/atomic"
+)
+
+const (
+	ColorModePalette = "palette"
+	ColorModeRandom  = "random"
+)
+
+//players keep clear of the virus hue by this many degrees
+const virusHueGap = 25
+
+//palette colors darker than this hide the name on the cell
+const minColorLuminance = 0.05
+
+//palette colors closer than this in rgb look alike
+const minColorDistance = 0.1
+
+//goldenAngle steps the hue so that players joining one after another never look alike
+const goldenAngle = 137.508
+
+var colorSeq = uint64(rand.Int63())
+
+//playerColor returns a saturated color of middle lightness, readable with black or white text
+func playerColor() string {
+	seq := atomic.AddUint64(&colorSeq, 1)
+	if Config.ColorMode == ColorModePalette && len(Config.PlayerColors) > 0 {
+		return Config.PlayerColors[seq%uint64(len(Config.PlayerColors))]
+	}
+	virusHue := util.Hue(Config.VirusColor)
+	hue := math.Mod(float64(seq%360)*goldenAngle, 360)
+	if util.HueDistance(hue, virusHue) < virusHueGap {
+		hue += 2 * virusHueGap
+	}
+	return util.HSL(hue, 0.65+rand.Float64()*0.25, 0.42+rand.Float64()*0.16)
+}
+
+//loadPlayerColors drops the palette colors that are malformed, too dark, close to the virus hue or to a color before them
+func loadPlayerColors(colors []string) []string {
+	valid := make([]string, 0, len(colors))
+	for _, color := range colors {
+		if e := checkPlayerColor(color, valid); e != nil {
+			Log.WithError(e).WithField("color", color).Warn("player color skipped")
+			continue
+		}
+		valid = append(valid, color)
+	}
+	return valid
+}
+
+func checkPlayerColor(color string, valid []string) error {
+	if _, _, _, ok := util.ParseColor(color); !ok {
+		return errors.New("not a #rrggbb color")
+	}
+	if util.Luminance(color) < minColorLuminance {
+		return errors.New("too dark")
+	}
+	if util.HueDistance(util.Hue(color), util.Hue(Config.VirusColor)) < virusHueGap {
+		return errors.New("too close to the virus color")
+	}
+	for _, v := range valid {
+		if util.ColorDistance(color, v) < minColorDistance {
+			return errors.New("too close to " + v)
+		}
+	}
+	return nil
+}
+
+//foodColor is pale, so food never passes for a player
+func foodColor() string {
+	return util.RandomHSL(0.5, 0.8, 0.74, 0.84)
+}
+
+//massFoodColor is a lighter shade of the player who fired it
+func massFoodColor(playerColor string) string {
+	return util.Lighten(playerColor, 0.3)
+}
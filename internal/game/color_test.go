@@ -0,0 +1,27 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadPlayerColors(t *testing.T) {
+	colors := []string{
+		"#f44336",
+		"#1a1a1a", //too dark
+		"#4caf50", //virus hue
+		"#8bc34a", //virus hue
+		"#f34235", //like the first one
+		"red",     //malformed
+		"#2196f3",
+	}
+	if valid := loadPlayerColors(colors); !reflect.DeepEqual(valid, []string{"#f44336", "#2196f3"}) {
+		t.Fatal("palette", valid)
+	}
+	if len(Config.PlayerColors) == 0 {
+		t.Fatal("default palette filtered out")
+	}
+	if valid := loadPlayerColors(Config.PlayerColors); len(valid) != len(Config.PlayerColors) {
+		t.Fatal("default palette has skipped colors", valid)
+	}
+}
@@ -3,11 +3,17 @@ package game
 import (
 	"github.com/spf13/viper"
 	"go-agar/internal/util"
+	"runtime"
 	"time"
 )
 
 type config struct {
 	Port                    int
+	Host                    string
+	TLSCert                 string
+	TLSKey                  string
+	TLSSelfSigned           bool
+	HTTPRedirectPort        int
 	Debug                   bool
 	BattlePlayerLimit       int
 	TickRate                int
@@ -40,6 +46,53 @@ type config struct {
 	FireFoodSpeed           float64
 	SplitSpeed              float64
 	AnonymousUserNamePrefix string
+	PlayerNameMaxLength     int
+	ReservedNames           []string
+	BannedNameWords         []string
+	DuplicateNames          string
+	PingInterval            time.Duration
+	LatencySmoothing        float64
+	MaxLatency              time.Duration
+	MaxLatencyDuration      time.Duration
+	AdminToken              string
+	SessionQueueSize        int
+	EventQueueSize          int
+	WriteTimeout            time.Duration
+	MaxStaleStatus          int
+	DefaultRoomNamePrefix   string
+	RoomNameMaxLength       int
+	MatchMassWeight         float64
+	BattleIdleTimeout       time.Duration
+	Workers                 []string
+	WorkerListen            string
+	WorkerSecret            string
+	WorkerPollInterval      time.Duration
+	WorkerDialTimeout       time.Duration
+	TickWorkers             int
+	MaxCatchUpTicks         int
+	AllowedOrigins          []string
+	MaxConnectionsPerIP     int
+	MaxMessageSize          int64
+	MaxMessagesPerSecond    int
+	MaxRooms                int
+	MaxRoomsPerIP           int
+	MaxFailedCodeLookups    int
+	ShutdownTimeout         time.Duration
+	RespawnDelay            time.Duration
+	SpawnProtection         time.Duration
+	SpawnAttempts           int
+	SpawnSafeDistance       float64
+	BanDuration             time.Duration
+	TrustProxyHeaders       bool
+	PlayerColors            []string
+	ColorMode               string
+	SkinDir                 string
+	SkinScanInterval        time.Duration
+	AssetDir                string
+	AssetMaxAge             time.Duration
+	LogLevel                string
+	LogFormat               string
+	Achievements            []*Achievement
 }
 
 var (
@@ -53,6 +106,11 @@ func init() {
 	viper.ReadInConfig()
 	Config = &config{
 		Port:                    viper.GetInt("Port"),
+		Host:                    viper.GetString("Host"),
+		TLSCert:                 viper.GetString("TLSCert"),
+		TLSKey:                  viper.GetString("TLSKey"),
+		TLSSelfSigned:           viper.GetBool("TLSSelfSigned"),
+		HTTPRedirectPort:        viper.GetInt("HTTPRedirectPort"),
 		Debug:                   viper.GetBool("Debug"),
 		BattlePlayerLimit:       viper.GetInt("BattlePlayerLimit"),
 		TickRate:                viper.GetInt("TickRate"),
@@ -85,13 +143,71 @@ func init() {
 		FireFoodSpeed:           viper.GetFloat64("FireFoodSpeed"),
 		SplitSpeed:              viper.GetFloat64("SplitSpeed"),
 		AnonymousUserNamePrefix: viper.GetString("AnonymousUserNamePrefix"),
+		PlayerNameMaxLength:     viper.GetInt("PlayerNameMaxLength"),
+		ReservedNames:           viper.GetStringSlice("ReservedNames"),
+		BannedNameWords:         viper.GetStringSlice("BannedNameWords"),
+		DuplicateNames:          viper.GetString("DuplicateNames"),
+		PingInterval:            viper.GetDuration("PingInterval"),
+		LatencySmoothing:        viper.GetFloat64("LatencySmoothing"),
+		MaxLatency:              viper.GetDuration("MaxLatency"),
+		MaxLatencyDuration:      viper.GetDuration("MaxLatencyDuration"),
+		AdminToken:              viper.GetString("AdminToken"),
+		SessionQueueSize:        viper.GetInt("SessionQueueSize"),
+		EventQueueSize:          viper.GetInt("EventQueueSize"),
+		WriteTimeout:            viper.GetDuration("WriteTimeout"),
+		MaxStaleStatus:          viper.GetInt("MaxStaleStatus"),
+		DefaultRoomNamePrefix:   viper.GetString("DefaultRoomNamePrefix"),
+		RoomNameMaxLength:       viper.GetInt("RoomNameMaxLength"),
+		MatchMassWeight:         viper.GetFloat64("MatchMassWeight"),
+		BattleIdleTimeout:       viper.GetDuration("BattleIdleTimeout"),
+		Workers:                 viper.GetStringSlice("Workers"),
+		WorkerListen:            viper.GetString("WorkerListen"),
+		WorkerSecret:            viper.GetString("WorkerSecret"),
+		WorkerPollInterval:      viper.GetDuration("WorkerPollInterval"),
+		WorkerDialTimeout:       viper.GetDuration("WorkerDialTimeout"),
+		TickWorkers:             viper.GetInt("TickWorkers"),
+		MaxCatchUpTicks:         viper.GetInt("MaxCatchUpTicks"),
+		AllowedOrigins:          viper.GetStringSlice("AllowedOrigins"),
+		MaxConnectionsPerIP:     viper.GetInt("MaxConnectionsPerIP"),
+		MaxMessageSize:          viper.GetInt64("MaxMessageSize"),
+		MaxMessagesPerSecond:    viper.GetInt("MaxMessagesPerSecond"),
+		MaxRooms:                viper.GetInt("MaxRooms"),
+		MaxRoomsPerIP:           viper.GetInt("MaxRoomsPerIP"),
+		MaxFailedCodeLookups:    viper.GetInt("MaxFailedCodeLookups"),
+		ShutdownTimeout:         viper.GetDuration("ShutdownTimeout"),
+		RespawnDelay:            viper.GetDuration("RespawnDelay"),
+		SpawnProtection:         viper.GetDuration("SpawnProtection"),
+		SpawnAttempts:           viper.GetInt("SpawnAttempts"),
+		SpawnSafeDistance:       viper.GetFloat64("SpawnSafeDistance"),
+		BanDuration:             viper.GetDuration("BanDuration"),
+		TrustProxyHeaders:       viper.GetBool("TrustProxyHeaders"),
+		PlayerColors:            viper.GetStringSlice("PlayerColors"),
+		ColorMode:               viper.GetString("ColorMode"),
+		SkinDir:                 viper.GetString("SkinDir"),
+		SkinScanInterval:        viper.GetDuration("SkinScanInterval"),
+		AssetDir:                viper.GetString("AssetDir"),
+		AssetMaxAge:             viper.GetDuration("AssetMaxAge"),
+		LogLevel:                viper.GetString("LogLevel"),
+		LogFormat:               viper.GetString("LogFormat"),
 	}
+	initLog()
+	Config.PlayerColors = loadPlayerColors(Config.PlayerColors)
+	Config.Achievements = loadAchievements()
 }
 
 func setDefaultConfig() {
 	defaultPlayerMass := float64(10)
 	slowBase := 4.5
 	viper.SetDefault("Port", 38888)
+	//address to bind, empty means all interfaces
+	viper.SetDefault("Host", "")
+	//serve https when both files are set
+	viper.SetDefault("TLSCert", "")
+	viper.SetDefault("TLSKey", "")
+	//serve https with a generated certificate when no files are set, for development only
+	viper.SetDefault("TLSSelfSigned", false)
+	//with https, plain http on this port redirects to it, 0 disables
+	viper.SetDefault("HTTPRedirectPort", 0)
 	viper.SetDefault("Debug", false)
 	viper.SetDefault("BattlePlayerLimit", 50)
 	viper.SetDefault("TickRate", 60)
@@ -124,4 +240,88 @@ func setDefaultConfig() {
 	viper.SetDefault("FireFoodSpeed", 25)
 	viper.SetDefault("SplitSpeed", 25)
 	viper.SetDefault("AnonymousUserNamePrefix", "u")
+	viper.SetDefault("PlayerNameMaxLength", 16)
+	//names nobody may take, compared ignoring case
+	viper.SetDefault("ReservedNames", []string{"admin", "administrator", "moderator", "server", "system"})
+	//names containing any of these words are refused, compared ignoring case
+	viper.SetDefault("BannedNameWords", []string{})
+	//a name already in the battle gets a number appended with suffix, or is refused with reject
+	viper.SetDefault("DuplicateNames", DuplicateNamesSuffix)
+	viper.SetDefault("PingInterval", 2*time.Second)
+	viper.SetDefault("LatencySmoothing", 0.125)
+	//0 means never kick for latency
+	viper.SetDefault("MaxLatency", 0)
+	viper.SetDefault("MaxLatencyDuration", 10*time.Second)
+	//admin api is disabled while empty
+	viper.SetDefault("AdminToken", "")
+	viper.SetDefault("SessionQueueSize", 64)
+	//battle events buffered for a subscriber, others than kills and achievements are dropped past it
+	viper.SetDefault("EventQueueSize", 1024)
+	viper.SetDefault("WriteTimeout", 2*time.Second)
+	//status frames replaced in a row before a lagging client is disconnected
+	viper.SetDefault("MaxStaleStatus", 180)
+	viper.SetDefault("DefaultRoomNamePrefix", "room-")
+	viper.SetDefault("RoomNameMaxLength", 20)
+	//how much a difference in average mass counts against a room when matchmaking, 0 means player count only
+	viper.SetDefault("MatchMassWeight", 0.1)
+	//a battle without players is stopped after this, 0 means never
+	viper.SetDefault("BattleIdleTimeout", 30*time.Second)
+	//battle worker addresses, battles run in this process while empty
+	viper.SetDefault("Workers", []string{})
+	//workers trust the gateway with client addresses, keep them off public interfaces
+	viper.SetDefault("WorkerListen", "127.0.0.1:38900")
+	//shared by the gateway and its workers, which refuse to run without it
+	viper.SetDefault("WorkerSecret", "")
+	viper.SetDefault("WorkerPollInterval", time.Second)
+	viper.SetDefault("WorkerDialTimeout", 2*time.Second)
+	//goroutines sharing the per player work of a tick
+	viper.SetDefault("TickWorkers", runtime.NumCPU())
+	//ticks simulated at once to catch up after a stall, beyond that the backlog is dropped
+	viper.SetDefault("MaxCatchUpTicks", 5)
+	//origins other than the host of the server allowed to open a game, "*" allows any
+	viper.SetDefault("AllowedOrigins", []string{})
+	//concurrent game connections from one ip, 0 means no limit
+	viper.SetDefault("MaxConnectionsPerIP", 0)
+	//bytes of a single client message, larger ones close the connection
+	viper.SetDefault("MaxMessageSize", 4096)
+	//messages a client may send in one second before it is disconnected, 0 means unlimited,
+	//the web client sends up to TickRate moves a second so keep it well above that
+	viper.SetDefault("MaxMessagesPerSecond", 200)
+	//rooms opened with create or private that may be running at once, in all and per ip of their creator, 0 means no limit.
+	//With workers each worker counts its own rooms
+	viper.SetDefault("MaxRooms", 200)
+	viper.SetDefault("MaxRoomsPerIP", 3)
+	//private codes an ip may get wrong in a minute before its code lookups are refused, 0 means no limit
+	viper.SetDefault("MaxFailedCodeLookups", 10)
+	//how long sessions get to receive the shutdown notice before the process exits
+	viper.SetDefault("ShutdownTimeout", 5*time.Second)
+	//how long a dead player waits before it may respawn in the same session
+	viper.SetDefault("RespawnDelay", 3*time.Second)
+	//a player that just spawned can not eat, be eaten or pop viruses for this long
+	viper.SetDefault("SpawnProtection", 3*time.Second)
+	//random positions tried for a new player, the first one this far from heavier cells and viruses is taken
+	viper.SetDefault("SpawnAttempts", 20)
+	viper.SetDefault("SpawnSafeDistance", 400)
+	//how long a ban added without a duration lasts
+	viper.SetDefault("BanDuration", time.Hour)
+	//take the client ip from X-Forwarded-For and X-Real-Ip, only behind a trusted proxy
+	viper.SetDefault("TrustProxyHeaders", false)
+	//colors a player may pick when joining, too dark ones, ones close to the virus color or to another one are skipped
+	viper.SetDefault("PlayerColors", []string{
+		"#f44336", "#e91e63", "#9c27b0", "#673ab7", "#3f51b5", "#2196f3", "#03a9f4", "#00bcd4",
+		"#009688", "#cddc39", "#ffeb3b", "#ffc107", "#ff9800", "#ff5722",
+	})
+	//how players who pick no color get one, palette takes turns through PlayerColors, random spreads hues around the wheel
+	viper.SetDefault("ColorMode", ColorModePalette)
+	//images in this directory can be picked as skins, new ones show up after the next scan
+	viper.SetDefault("SkinDir", "skins")
+	viper.SetDefault("SkinScanInterval", 10*time.Second)
+	//serve the web client from this directory instead of the embedded one, such as web/static while developing
+	viper.SetDefault("AssetDir", "")
+	//how long browsers may cache scripts, styles and images, pages are always revalidated
+	viper.SetDefault("AssetMaxAge", time.Hour)
+	//panic, fatal, error, warn, info, debug or trace, Debug forces debug
+	viper.SetDefault("LogLevel", "info")
+	//text or json
+	viper.SetDefault("LogFormat", "text")
 }
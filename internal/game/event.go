@@ -0,0 +1,174 @@
+package game
+
+//Event is one of the event types below, they are emitted on the battle goroutine in the tick they happen
+type Event interface {
+	event()
+}
+
+//PlayerEaten is emitted when a cell of the eater swallows cells of the victim, Killed is set when none is left
+type PlayerEaten struct {
+	Tick     uint64
+	EaterId  string
+	Eater    string
+	VictimId string
+	Victim   string
+	Cells    int
+	Mass     float64
+	Killed   bool
+}
+
+//VirusPopped is a player cell splitting on a virus at X, Y
+type VirusPopped struct {
+	Tick     uint64
+	PlayerId string
+	Player   string
+	X        float64
+	Y        float64
+}
+
+type PlayerJoined struct {
+	Tick     uint64
+	PlayerId string
+	Player   string
+}
+
+//PlayerLeft is emitted when the player is removed, Died tells it was because it lost all its mass
+type PlayerLeft struct {
+	Tick     uint64
+	PlayerId string
+	Player   string
+	Died     bool
+}
+
+//MassFired sums the mass a player fired in a tick
+type MassFired struct {
+	Tick     uint64
+	PlayerId string
+	Player   string
+	Count    int
+	Mass     float64
+}
+
+//AchievementUnlocked is emitted once per player and achievement
+type AchievementUnlocked struct {
+	Tick        uint64
+	PlayerId    string
+	Player      string
+	Achievement *Achievement
+}
+
+func (*PlayerEaten) event()         {}
+func (*VirusPopped) event()         {}
+func (*PlayerJoined) event()        {}
+func (*PlayerLeft) event()          {}
+func (*MassFired) event()           {}
+func (*AchievementUnlocked) event() {}
+
+type subscriber struct {
+	events chan Event
+	accept func(Event) bool
+	//pending holds events that must be delivered until events has room for them
+	pending []Event
+	dropped int
+}
+
+//mustDeliver tells the events players have to be told about, they wait for a slow subscriber instead of being dropped
+func mustDeliver(e Event) bool {
+	switch e := e.(type) {
+	case *PlayerEaten:
+		return e.Killed
+	case *AchievementUnlocked:
+		return true
+	}
+	return false
+}
+
+//Subscribe returns the events accept takes, all of them when it is nil, until cancel is called or the battle stops, which closes the channel.
+//A subscriber that does not keep up with size buffered events misses the following ones, except kills and achievements which are kept until it catches up
+func (b *Battle) Subscribe(size int, accept func(Event) bool) (<-chan Event, func()) {
+	sub := &subscriber{events: make(chan Event, size), accept: accept}
+	added := make(chan byte, 1)
+	subscribe := func() {
+		b.subscribers = append(b.subscribers, sub)
+		added <- 1
+	}
+	if !b.exec(subscribe) {
+		close(sub.events)
+		return sub.events, func() {}
+	}
+	select {
+	case <-added:
+	case <-b.done:
+		select {
+		case <-added:
+			//closed by the battle on stop
+		default:
+			close(sub.events)
+		}
+	}
+	return sub.events, func() {
+		b.exec(func() { b.unsubscribe(sub) })
+	}
+}
+
+func (b *Battle) unsubscribe(sub *subscriber) {
+	for i, s := range b.subscribers {
+		if s == sub {
+			b.subscribers = append(b.subscribers[:i], b.subscribers[i+1:]...)
+			close(sub.events)
+			return
+		}
+	}
+}
+
+func (b *Battle) emit(e Event) {
+	for _, sub := range b.subscribers {
+		if sub.accept != nil && !sub.accept(e) {
+			continue
+		}
+		//pending events go first so the order is kept
+		if sub.flush() {
+			select {
+			case sub.events <- e:
+				continue
+			default:
+			}
+		}
+		if mustDeliver(e) {
+			sub.pending = append(sub.pending, e)
+			continue
+		}
+		sub.dropped++
+		if sub.dropped == 1 {
+			b.log.Warn("event subscriber too slow, events dropped")
+		}
+	}
+}
+
+//flush moves pending events to the channel as far as it has room, it tells whether none is left
+func (sub *subscriber) flush() bool {
+	for len(sub.pending) > 0 {
+		select {
+		case sub.events <- sub.pending[0]:
+			sub.pending[0] = nil
+			sub.pending = sub.pending[1:]
+		default:
+			return false
+		}
+	}
+	sub.pending = nil
+	return true
+}
+
+func (b *Battle) flushSubscribers() {
+	for _, sub := range b.subscribers {
+		sub.flush()
+	}
+}
+
+func (b *Battle) closeSubscribers() {
+	for _, sub := range b.subscribers {
+		close(sub.events)
+	}
+	b.subscribers = nil
+}
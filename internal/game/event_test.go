@@ -0,0 +1,42 @@
+package game
+
+import "testing"
+
+//a slow subscriber misses what it may miss but gets every kill, in order
+func TestEventsKeptForSlowSubscriber(t *testing.T) {
+	b := newBattle("", false)
+	sub := &subscriber{events: make(chan Event, 2), accept: func(e Event) bool {
+		_, fired := e.(*MassFired)
+		return !fired
+	}}
+	b.subscribers = append(b.subscribers, sub)
+	for i := 0; i < 10; i++ {
+		b.emit(&MassFired{Tick: uint64(i)})
+		b.emit(&VirusPopped{Tick: uint64(i)})
+		b.emit(&PlayerEaten{Tick: uint64(i), Killed: true})
+	}
+	if sub.dropped == 0 {
+		t.Fatal("nothing dropped")
+	}
+	var kills []uint64
+	for len(kills) < 10 {
+		select {
+		case e := <-sub.events:
+			switch e := e.(type) {
+			case *MassFired:
+				t.Fatal("filtered event delivered")
+			case *PlayerEaten:
+				kills = append(kills, e.Tick)
+			}
+		default:
+			if b.flushSubscribers(); len(sub.events) == 0 {
+				t.Fatal("kills lost", kills)
+			}
+		}
+	}
+	for i, tick := range kills {
+		if tick != uint64(i) {
+			t.Fatal("kills out of order", kills)
+		}
+	}
+}
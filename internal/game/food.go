@@ -21,7 +21,7 @@ func NewFood() *Food {
 		Y:      y,
 		Radius: radius,
 		mass:   mass,
-		Color:  util.Color(),
+		Color:  foodColor(),
 	}
 }
 
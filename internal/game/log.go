@@ -0,0 +1,25 @@
+package game
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+//Log is the root logger, gateways, sessions and battles log through entries derived from it
+var Log = logrus.New()
+
+func initLog() {
+	if Config.LogFormat == "json" {
+		Log.SetFormatter(&logrus.JSONFormatter{})
+	} else {
+		Log.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	}
+	level, e := logrus.ParseLevel(Config.LogLevel)
+	if e != nil {
+		Log.WithField("level", Config.LogLevel).Warn("unknown log level, use info")
+		level = logrus.InfoLevel
+	}
+	if Config.Debug {
+		level = logrus.DebugLevel
+	}
+	Log.SetLevel(level)
+}
@@ -3,6 +3,7 @@ package game
 import (
 	"go-agar/internal/util"
 	"math"
+	"time"
 )
 
 type MassFood struct {
@@ -22,18 +23,19 @@ func NewMassFood(player *Player) *MassFood {
 	return &MassFood{
 		Id:     util.GenId(),
 		player: player,
-		Color:  player.Color,
+		Color:  massFoodColor(player.Color),
 	}
 }
 
-func (mf *MassFood) Move() {
+func (mf *MassFood) Move(dt time.Duration) {
 	if mf.speed == 0 {
 		return
 	}
+	scale := tickScale(dt)
 	deg := math.Atan2(mf.targetY, mf.targetX)
-	deltaX := mf.speed * math.Cos(deg)
-	deltaY := mf.speed * math.Sin(deg)
-	mf.speed -= Config.FireFoodSpeedSlow
+	deltaX := mf.speed * math.Cos(deg) * scale
+	deltaY := mf.speed * math.Sin(deg) * scale
+	mf.speed -= Config.FireFoodSpeedSlow * scale
 	if mf.speed < 0 {
 		mf.speed = 0
 	}
@@ -0,0 +1,33 @@
+package game
+
+import (
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+)
+
+//confusables maps lower case letters of other scripts to the latin letter they pass for
+var confusables = map[rune]rune{
+	//cyrillic
+	'а': 'a', 'в': 'b', 'с': 'c', 'ԁ': 'd', 'е': 'e', 'һ': 'h', 'н': 'h', 'і': 'i', 'ј': 'j', 'к': 'k',
+	'ӏ': 'l', 'м': 'm', 'о': 'o', 'р': 'p', 'ԛ': 'q', 'ѕ': 's', 'т': 't', 'у': 'y', 'ԝ': 'w', 'х': 'x',
+	//greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'h', 'ι': 'i', 'κ': 'k', 'μ': 'm', 'ν': 'n', 'ο': 'o', 'ρ': 'p',
+	'τ': 't', 'υ': 'y', 'χ': 'x', 'ζ': 'z',
+}
+
+//NameKey is what names are compared by, names that look alike get the same key:
+//compatibility forms such as fullwidth letters are folded, case and accents dropped and confusable letters mapped to latin
+func NameKey(name string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(norm.NFKC.String(name))) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if latin, ok := confusables[r]; ok {
+			r = latin
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
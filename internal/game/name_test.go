@@ -0,0 +1,27 @@
+package game
+
+import "testing"
+
+func TestNameKey(t *testing.T) {
+	for _, name := range []string{"admin", "Admin", "ａｄｍｉｎ", "аdmin", "ΑDMIN", "ádmín"} {
+		if key := NameKey(name); key != "admin" {
+			t.Errorf("key of %q is %q", name, key)
+		}
+	}
+	if NameKey("adam") == NameKey("admin") {
+		t.Error("different names share a key")
+	}
+}
+
+func TestLookAlikeNamesTaken(t *testing.T) {
+	b := busyBattle(t, 2)
+	b.players[0].Name = "bob"
+	for _, name := range []string{"BOB", "ｂｏｂ", "bоb"} {
+		if !b.nameTaken(name) {
+			t.Errorf("%q not taken", name)
+		}
+	}
+	if b.nameTaken("rob") {
+		t.Error("rob taken")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"go-agar/internal/util"
 	"math"
 	"sort"
+	"sync/atomic"
 	"time"
 )
 
@@ -15,16 +16,52 @@ type Player struct {
 	Y                float64
 	targetX          float64
 	targetY          float64
+	lastInput        int64
 	Color            string
 	TextColor        string
-	lastSplit        time.Time
+	Skin             string
+	lastSplit        time.Duration
+	simTime          time.Duration
 	fireFood         chan *MassFood
 	split            chan *Cell
 	MassTotal        float64
-	VisibleFoods     []*Food
-	VisibleMassFoods []*MassFood
-	VisibleCells     []*Cell
-	VisibleViruses   []*Virus
+	joinTime         time.Time
+	protectedUntil   time.Time
+	stats            Stats
+	unlocked         map[string]bool
+	achievements     []*Achievement
+	killer           string
+	death            *Death
+	visibleFoods     []*Food
+	visibleMassFoods []*MassFood
+	visibleCells     []*Cell
+	visibleViruses   []*Virus
+	view             atomic.Value
+}
+
+//PlayerView is an immutable copy of what a player sees, taken at the end of each tick
+type PlayerView struct {
+	Tick      uint64
+	Time      time.Time
+	Name      string
+	X         float64
+	Y         float64
+	MassTotal float64
+	Died      bool
+	Death     *Death
+	LastInput int64
+	Cells     []Cell
+	Foods     []Food
+	MassFoods []MassFood
+	Viruses   []Virus
+	//Achievements lists all the player unlocked so far, so none is missed by whoever skips a view
+	Achievements []*Achievement
+}
+
+//Death tells how a player did, Killer is empty when the player was not eaten by another one
+type Death struct {
+	Killer string
+	Stats  Stats
 }
 
 type personSlice []*Player
@@ -37,33 +74,112 @@ func SortPlayers(players []*Player) {
 	sort.Sort(personSlice(players))
 }
 
-func NewPlayer(name string) *Player {
+//NewPlayer creates a player with a color of ColorMode unless one is given, color and skin must be validated by the caller
+func NewPlayer(name, color, skin string) *Player {
 	id := util.GenId()
 	if name == "" {
 		name = Config.AnonymousUserNamePrefix + id[:6]
 	}
 	mass := Config.DefaultPlayerMass
 	radius := util.MassToRadius(mass)
-	//x, y := util.RandomPosition(radius, Config.GameWidth, Config.GameHeight)
-	x, y := Config.GameWidth/2, Config.GameHeight/2
+	x, y := util.RandomPosition(radius, Config.GameWidth, Config.GameHeight)
+	if color == "" {
+		color = playerColor()
+	}
 
 	p := &Player{
-		Id:            id,
-		Name:          name,
-		X:             x,
-		Y:             y,
-		Color:         util.Color(),
-		TextColor:     "#000000",
-		fireFood:      make(chan *MassFood, Config.CellMaxNum*10),
-		split:         make(chan *Cell, Config.CellMaxNum),
-		MassTotal:     mass,
+		Id:        id,
+		Name:      name,
+		X:         x,
+		Y:         y,
+		Color:     color,
+		TextColor: util.TextColor(color),
+		Skin:      skin,
+		fireFood:  make(chan *MassFood, Config.CellMaxNum*10),
+		split:     make(chan *Cell, Config.CellMaxNum),
+		MassTotal: mass,
+		joinTime:  time.Now(),
+		stats:     Stats{PeakMass: mass, MaxCells: 1},
+		unlocked:  make(map[string]bool),
 	}
 	c := p.addCell()
 	c.mass = mass
 	c.Radius = radius
+	p.updateView(0, time.Now())
 	return p
 }
 
+//spawn places a player that has not moved yet and starts its protection
+func (p *Player) spawn(x, y float64, now time.Time) {
+	p.X, p.Y = x, y
+	for _, c := range p.cells {
+		c.X, c.Y = x, y
+	}
+	p.protectedUntil = now.Add(Config.SpawnProtection)
+}
+
+func (p *Player) rename(name string) {
+	p.Name = name
+	for _, c := range p.cells {
+		c.Name = name
+	}
+}
+
+//View returns the latest snapshot, it is safe to call from any goroutine
+func (p *Player) View() *PlayerView {
+	return p.view.Load().(*PlayerView)
+}
+
+func (p *Player) updateView(tick uint64, now time.Time) {
+	if p.MassTotal > p.stats.PeakMass {
+		p.stats.PeakMass = p.MassTotal
+	}
+	v := &PlayerView{
+		Tick:         tick,
+		Time:         now,
+		Name:         p.Name,
+		X:            p.X,
+		Y:            p.Y,
+		MassTotal:    p.MassTotal,
+		Died:         p.IsDied(),
+		Death:        p.death,
+		LastInput:    p.lastInput,
+		Achievements: p.achievements[:len(p.achievements):len(p.achievements)],
+		Cells:        make([]Cell, len(p.visibleCells)),
+		Foods:        make([]Food, len(p.visibleFoods)),
+		MassFoods:    make([]MassFood, len(p.visibleMassFoods)),
+		Viruses:      make([]Virus, len(p.visibleViruses)),
+	}
+	for i, c := range p.visibleCells {
+		v.Cells[i] = *c
+	}
+	for i, f := range p.visibleFoods {
+		v.Foods[i] = *f
+	}
+	for i, mf := range p.visibleMassFoods {
+		v.MassFoods[i] = *mf
+	}
+	for i, vi := range p.visibleViruses {
+		v.Viruses[i] = *vi
+	}
+	p.view.Store(v)
+}
+
+//die records how the player did, the battle drops it right after
+func (p *Player) die(tick uint64, now time.Time) {
+	p.stats.SurvivalTime = now.Sub(p.joinTime)
+	p.death = &Death{
+		Killer: p.killer,
+		Stats:  p.stats,
+	}
+	p.updateView(tick, now)
+}
+
+//IsProtected tells whether the player just spawned, it neither eats nor is eaten by other players and passes through viruses
+func (p *Player) IsProtected(now time.Time) bool {
+	return now.Before(p.protectedUntil)
+}
+
 func (p *Player) IsDied() bool {
 	return len(p.cells) == 0 || int(p.MassTotal) == 0
 }
@@ -89,6 +205,13 @@ func (p *Player) MoveTo(x, y float64) {
 	p.targetY = y
 }
 
+//Input records the sequence number of the latest processed move, echoed back so the client can reconcile
+func (p *Player) Input(seq int64) {
+	if seq > p.lastInput {
+		p.lastInput = seq
+	}
+}
+
 func (p *Player) FireFood() {
 	for _, c := range p.cells {
 		fireMass := Config.FireFoodRate * c.mass
@@ -108,7 +231,14 @@ func (p *Player) FireFood() {
 		mf.targetX = p.X - c.X + p.targetX
 		mf.targetY = p.Y - c.Y + p.targetY
 		mf.speed = Config.FireFoodSpeed
-		p.fireFood <- mf
+		select {
+		case p.fireFood <- mf:
+			p.stats.MassFired += fireMass
+		default:
+			c.mass += fireMass
+			p.MassTotal += fireMass
+			return
+		}
 	}
 }
 
@@ -128,7 +258,7 @@ func (p *Player) UpdateVisibleFoods(foods [] *Food) {
 			i++
 		}
 	}
-	p.VisibleFoods = visibleFoods[:i]
+	p.visibleFoods = visibleFoods[:i]
 }
 
 func (p *Player) UpdateVisibleMassFoods(massFoods [] *MassFood) {
@@ -143,7 +273,7 @@ func (p *Player) UpdateVisibleMassFoods(massFoods [] *MassFood) {
 			i++
 		}
 	}
-	p.VisibleMassFoods = visibleMassFoods[:i]
+	p.visibleMassFoods = visibleMassFoods[:i]
 }
 
 func (p *Player) UpdateVisibleViruses(viruses []*Virus) {
@@ -158,7 +288,7 @@ func (p *Player) UpdateVisibleViruses(viruses []*Virus) {
 			i++
 		}
 	}
-	p.VisibleViruses = visibleViruses[:i]
+	p.visibleViruses = visibleViruses[:i]
 }
 
 func (p *Player) UpdateVisibleCells(players []*Player) {
@@ -176,10 +306,12 @@ func (p *Player) UpdateVisibleCells(players []*Player) {
 			}
 		}
 	}
-	p.VisibleCells = visibleCells[:i]
+	p.visibleCells = visibleCells[:i]
 }
 
-func (p *Player) Update() {
+//Update advances the player by dt, simTime is the simulated time of the battle when the tick starts
+func (p *Player) Update(simTime, dt time.Duration) {
+	p.simTime = simTime
 	p.updateSplit()
 	x, y := float64(0), float64(0)
 	for i := 0; i < len(p.cells); i++ {
@@ -187,14 +319,20 @@ func (p *Player) Update() {
 		if c.speed == 0 {
 			c.speed = Config.CellDefaultSpeed
 		}
-		p.moveCell(c)
-		p.mergeCell(i)
+		prevX, prevY := c.X, c.Y
+		p.moveCell(c, dt)
+		p.mergeCell(i, dt)
 		if len(p.cells) > i {
 			p.borderReboundCell(c)
+			c.VX = (c.X - prevX) / dt.Seconds()
+			c.VY = (c.Y - prevY) / dt.Seconds()
 			x += c.X
 			y += c.Y
 		}
 	}
+	if len(p.cells) == 0 {
+		return
+	}
 	clen := float64(len(p.cells))
 	p.X = x / clen
 	p.Y = y / clen
@@ -211,7 +349,8 @@ func (p *Player) updateSplit() {
 	}
 }
 
-func (p *Player) moveCell(c *Cell) {
+func (p *Player) moveCell(c *Cell, dt time.Duration) {
+	scale := tickScale(dt)
 	targetX := p.X - c.X + p.targetX
 	targetY := p.Y - c.Y + p.targetY
 	dist := math.Sqrt(math.Pow(targetY, 2) + math.Pow(targetX, 2))
@@ -221,10 +360,10 @@ func (p *Player) moveCell(c *Cell) {
 	if c.speed <= Config.CellDefaultSpeed {
 		slowDown = util.Log(c.mass, Config.SlowBase) - Config.InitMassLog + 1
 	}
-	deltaY := c.speed * math.Sin(deg) / slowDown
-	deltaX := c.speed * math.Cos(deg) / slowDown
+	deltaY := c.speed * math.Sin(deg) / slowDown * scale
+	deltaX := c.speed * math.Cos(deg) / slowDown * scale
 	if c.speed > Config.CellDefaultSpeed {
-		c.speed -= 0.5
+		c.speed -= 0.5 * scale
 	}
 	//why 50 ?
 	if dist < (50 + c.Radius) {
@@ -235,10 +374,11 @@ func (p *Player) moveCell(c *Cell) {
 	c.X += deltaX
 }
 
-func (p *Player) mergeCell(i int) {
+func (p *Player) mergeCell(i int, dt time.Duration) {
 	c := p.cells[i]
+	push := tickScale(dt)
 	//merge or separate
-	mergePermit := p.lastSplit.Add(Config.MergeInterval).Before(time.Now())
+	mergePermit := p.simTime-p.lastSplit > Config.MergeInterval
 	for j := i + 1; j < len(p.cells); j++ {
 		c2 := p.cells[j]
 		distance := util.GetDistance(c.X, c.Y, 0, c2.X, c2.Y, 0)
@@ -253,14 +393,14 @@ func (p *Player) mergeCell(i int) {
 			continue
 		}
 		if c.X < c2.X {
-			c.X --
+			c.X -= push
 		} else if c.X > c2.X {
-			c.X ++
+			c.X += push
 		}
 		if c.Y < c2.Y {
-			c.Y --
+			c.Y -= push
 		} else if c.Y > c2.Y {
-			c.Y ++
+			c.Y += push
 		}
 	}
 }
@@ -299,7 +439,11 @@ func (p *Player) splitCell(c *Cell) {
 			nc.Radius = c.Radius
 			nc.speed = Config.SplitSpeed
 
-			p.lastSplit = time.Now()
+			p.lastSplit = p.simTime
+			p.stats.Splits++
+			if len(p.cells) > p.stats.MaxCells {
+				p.stats.MaxCells = len(p.cells)
+			}
 			return
 		}
 	}
@@ -0,0 +1,45 @@
+package game
+
+import (
+	"go-agar/internal/util"
+	"math"
+)
+
+//spawnPosition samples random positions and returns the first one clear of danger,
+//or the one farthest from it when the map is crowded
+func (b *Battle) spawnPosition(p *Player) (float64, float64) {
+	radius := util.MassToRadius(p.MassTotal)
+	bestX, bestY, best := p.X, p.Y, math.Inf(-1)
+	for i := 0; i < Config.SpawnAttempts; i++ {
+		x, y := util.RandomPosition(radius, Config.GameWidth, Config.GameHeight)
+		d := b.dangerDistance(x, y, radius, p.MassTotal)
+		if d >= Config.SpawnSafeDistance {
+			return x, y
+		}
+		if d > best {
+			bestX, bestY, best = x, y, d
+		}
+	}
+	return bestX, bestY
+}
+
+//dangerDistance is the gap to the closest cell heavier than mass or virus, edge to edge
+func (b *Battle) dangerDistance(x, y, radius, mass float64) float64 {
+	min := math.Inf(1)
+	for _, p := range b.players {
+		for _, c := range p.cells {
+			if c.mass <= mass {
+				continue
+			}
+			if d := util.GetDistance(x, y, radius, c.X, c.Y, c.Radius); d < min {
+				min = d
+			}
+		}
+	}
+	for _, v := range b.viruses {
+		if d := util.GetDistance(x, y, radius, v.X, v.Y, v.Radius); d < min {
+			min = d
+		}
+	}
+	return min
+}
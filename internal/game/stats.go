@@ -0,0 +1,71 @@
+package game
+
+import "time"
+
+//Stats is collected on the battle goroutine over the life of a player
+type Stats struct {
+	FoodsEaten   int           `json:"foodsEaten"`
+	MassFired    float64       `json:"massFired"`
+	Splits       int           `json:"splits"`
+	MaxCells     int           `json:"maxCells"`
+	CellsEaten   int           `json:"cellsEaten"`
+	PlayersEaten int           `json:"playersEaten"`
+	VirusesHit   int           `json:"virusesHit"`
+	PeakMass     float64       `json:"peakMass"`
+	SurvivalTime time.Duration `json:"survivalTime"`
+}
+
+//names of the stats, used by achievement rules
+const (
+	StatFoodsEaten   = "foodsEaten"
+	StatMassFired    = "massFired"
+	StatSplits       = "splits"
+	StatMaxCells     = "maxCells"
+	StatCellsEaten   = "cellsEaten"
+	StatPlayersEaten = "playersEaten"
+	StatVirusesHit   = "virusesHit"
+	StatPeakMass     = "peakMass"
+	StatSurvivalTime = "survivalTime"
+)
+
+//Value returns the stat of that name, survival time in seconds
+func (s *Stats) Value(name string) (float64, bool) {
+	switch name {
+	case StatFoodsEaten:
+		return float64(s.FoodsEaten), true
+	case StatMassFired:
+		return s.MassFired, true
+	case StatSplits:
+		return float64(s.Splits), true
+	case StatMaxCells:
+		return float64(s.MaxCells), true
+	case StatCellsEaten:
+		return float64(s.CellsEaten), true
+	case StatPlayersEaten:
+		return float64(s.PlayersEaten), true
+	case StatVirusesHit:
+		return float64(s.VirusesHit), true
+	case StatPeakMass:
+		return s.PeakMass, true
+	case StatSurvivalTime:
+		return s.SurvivalTime.Seconds(), true
+	}
+	return 0, false
+}
+
+//Add sums the stats of another life into s, keeping the best peak mass and cell count
+func (s *Stats) Add(o Stats) {
+	s.FoodsEaten += o.FoodsEaten
+	s.MassFired += o.MassFired
+	s.Splits += o.Splits
+	s.CellsEaten += o.CellsEaten
+	s.PlayersEaten += o.PlayersEaten
+	s.VirusesHit += o.VirusesHit
+	s.SurvivalTime += o.SurvivalTime
+	if o.MaxCells > s.MaxCells {
+		s.MaxCells = o.MaxCells
+	}
+	if o.PeakMass > s.PeakMass {
+		s.PeakMass = o.PeakMass
+	}
+}
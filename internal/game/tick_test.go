@@ -0,0 +1,121 @@
+package game
+
+import (
+	"math/rand"
+	"reflect"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+//busyBattle fills a battle that is not running, its ticks are driven by the caller
+func busyBattle(tb testing.TB, players int) *Battle {
+	limit := Config.BattlePlayerLimit
+	Config.BattlePlayerLimit = players
+	tb.Cleanup(func() { Config.BattlePlayerLimit = limit })
+	b := newBattle("", false)
+	b.addVirus()
+	b.balance()
+	for i := 0; i < players; i++ {
+		p := NewPlayer("p"+strconv.Itoa(i), "", "")
+		if e := b.addPlayer(p); e != nil {
+			tb.Fatal(e)
+		}
+		p.MoveTo(rand.Float64()*400-200, rand.Float64()*400-200)
+	}
+	return b
+}
+
+//parallelWorkers is at least enough to take the parallel path on small hosts
+func parallelWorkers() int {
+	if n := runtime.NumCPU(); n > 4 {
+		return n
+	}
+	return 4
+}
+
+func setTickWorkers(tb testing.TB, workers int) {
+	old := Config.TickWorkers
+	Config.TickWorkers = workers
+	tb.Cleanup(func() { Config.TickWorkers = old })
+}
+
+func BenchmarkTick200(b *testing.B) {
+	step := time.Second / time.Duration(Config.TickRate)
+	modes := []struct {
+		name    string
+		workers int
+	}{
+		{"TickWorkers=1", 1},
+		{"TickWorkers=NumCPU", runtime.NumCPU()},
+	}
+	for _, mode := range modes {
+		b.Run(mode.name, func(b *testing.B) {
+			setTickWorkers(b, mode.workers)
+			battle := busyBattle(b, 200)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				battle.shortTickLoop(step)
+				battle.publish()
+			}
+		})
+	}
+}
+
+//views worked out in parallel must match the serial ones, whatever the order players are handled in
+func TestParallelViewsDeterministic(t *testing.T) {
+	step := time.Second / time.Duration(Config.TickRate)
+	b := busyBattle(t, 200)
+	setTickWorkers(t, 1)
+	for tick := 0; tick < 10; tick++ {
+		b.shortTickLoop(step)
+		Config.TickWorkers = 1
+		b.updateViews()
+		serial := make([]*PlayerView, len(b.players))
+		for i, p := range b.players {
+			serial[i] = p.View()
+		}
+		Config.TickWorkers = parallelWorkers()
+		b.updateViews()
+		for i, p := range b.players {
+			parallel := p.View()
+			if parallel == serial[i] {
+				t.Fatal("view not updated")
+			}
+			//the views only differ by when they were taken
+			parallel.Time = serial[i].Time
+			if !reflect.DeepEqual(parallel, serial[i]) {
+				t.Fatalf("tick %d: view of %s differs", tick, p.Name)
+			}
+		}
+	}
+}
+
+//merging waits for MergeInterval of simulated time, however fast the ticks are run
+func TestMergeAfterSimulatedInterval(t *testing.T) {
+	step := time.Second / time.Duration(Config.TickRate)
+	b := busyBattle(t, 1)
+	p := b.players[0]
+	p.MoveTo(0, 0)
+	p.cells[0].mass = 100
+	p.MassTotal = 100
+	p.SplitAll()
+	split := b.simTime
+	b.shortTickLoop(step)
+	if len(p.cells) != 2 {
+		t.Fatal("cells after split", len(p.cells))
+	}
+	for b.simTime-split < Config.MergeInterval {
+		b.shortTickLoop(step)
+		if len(p.cells) != 2 {
+			t.Fatal("merged after", b.simTime-split)
+		}
+	}
+	for i := 0; i < 5*Config.TickRate && len(p.cells) > 1; i++ {
+		b.shortTickLoop(step)
+	}
+	if len(p.cells) != 1 {
+		t.Fatal("not merged after", b.simTime-split)
+	}
+}
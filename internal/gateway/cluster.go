@@ -0,0 +1,285 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	"go-agar/internal/game"
+	"go-agar/pkg/protocol"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errNoWorker = errors.New("no worker available")
+
+type worker struct {
+	addr     string
+	alive    bool
+	lastSeen time.Time
+	status   *workerStatus
+}
+
+//workerRegistry keeps the load of every battle worker, refreshed by polling
+type workerRegistry struct {
+	workers  []*worker
+	locker   *sync.Mutex
+	done     chan byte
+	stopOnce *sync.Once
+}
+
+func newWorkerRegistry(addrs []string) *workerRegistry {
+	workers := make([]*worker, len(addrs))
+	for i, addr := range addrs {
+		workers[i] = &worker{
+			addr:   addr,
+			status: &workerStatus{},
+		}
+	}
+	return &workerRegistry{
+		workers:  workers,
+		locker:   &sync.Mutex{},
+		done:     make(chan byte),
+		stopOnce: &sync.Once{},
+	}
+}
+
+func (r *workerRegistry) watch() {
+	ticker := time.NewTicker(game.Config.WorkerPollInterval)
+	defer ticker.Stop()
+	r.pollAll()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			r.pollAll()
+		}
+	}
+}
+
+func (r *workerRegistry) stop() {
+	r.stopOnce.Do(func() {
+		close(r.done)
+	})
+}
+
+func (r *workerRegistry) pollAll() {
+	wg := &sync.WaitGroup{}
+	for _, w := range r.workers {
+		wg.Add(1)
+		go func(w *worker) {
+			defer wg.Done()
+			status, e := pollWorker(w.addr)
+			r.locker.Lock()
+			defer r.locker.Unlock()
+			if e != nil {
+				if w.alive {
+					game.Log.WithFields(logrus.Fields{"worker": w.addr, "error": e}).Warn("worker down")
+				}
+				w.alive = false
+				return
+			}
+			if !w.alive {
+				game.Log.WithField("worker", w.addr).Info("worker up")
+			}
+			w.alive = true
+			w.lastSeen = time.Now()
+			w.status = status
+		}(w)
+	}
+	wg.Wait()
+}
+
+//dialWorker connects to a worker and authenticates with WorkerSecret
+func dialWorker(addr string) (*frameConn, error) {
+	c, e := net.DialTimeout("tcp", addr, game.Config.WorkerDialTimeout)
+	if e != nil {
+		return nil, e
+	}
+	conn := newFrameConn(c)
+	e = conn.SetWriteDeadline(time.Now().Add(game.Config.WorkerDialTimeout))
+	if e == nil {
+		e = conn.WriteMessage(websocket.TextMessage, []byte(game.Config.WorkerSecret))
+	}
+	if e != nil {
+		conn.Close()
+		return nil, e
+	}
+	return conn, nil
+}
+
+func pollWorker(addr string) (*workerStatus, error) {
+	conn, e := dialWorker(addr)
+	if e != nil {
+		return nil, e
+	}
+	defer conn.Close()
+	if e := conn.SetDeadline(time.Now().Add(game.Config.WorkerDialTimeout)); e != nil {
+		return nil, e
+	}
+	if e := conn.WriteMessage(websocket.TextMessage, []byte(handshakeStatus)); e != nil {
+		return nil, e
+	}
+	_, data, e := conn.ReadMessage()
+	if e != nil {
+		return nil, e
+	}
+	status := &workerStatus{}
+	if e := json.Unmarshal(data, status); e != nil {
+		return nil, e
+	}
+	return status, nil
+}
+
+//pick returns the worker holding the requested room, or the least loaded one
+func (r *workerRegistry) pick(req *roomRequest) (*worker, error) {
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	if req.id != "" || req.code != "" {
+		key := ""
+		if req.code != "" {
+			key = codeKey(strings.ToUpper(req.code))
+		}
+		for _, w := range r.workers {
+			if !w.alive {
+				continue
+			}
+			for _, room := range w.status.Rooms {
+				if (req.id != "" && !room.Private && room.Id == req.id) || (key != "" && room.CodeKey == key) {
+					return w, nil
+				}
+			}
+		}
+		return nil, errRoomNotFound
+	}
+	var best *worker
+	for _, w := range r.workers {
+		if w.alive && (best == nil || w.status.Players < best.status.Players) {
+			best = w
+		}
+	}
+	if best == nil {
+		return nil, errNoWorker
+	}
+	//count the player now so a burst of joins spreads before the next poll
+	best.status.Players++
+	return best, nil
+}
+
+func (r *workerRegistry) rooms() []*game.BattleInfo {
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	rooms := make([]*game.BattleInfo, 0)
+	for _, w := range r.workers {
+		if !w.alive {
+			continue
+		}
+		for _, room := range w.status.Rooms {
+			if !room.Private {
+				rooms = append(rooms, &room.BattleInfo)
+			}
+		}
+	}
+	return rooms
+}
+
+//relaySession hands a browser connection over to a worker and copies messages both ways
+func (g *Gateway) relaySession(context *gin.Context, guardConn *guardConn) {
+	log := g.requestLog(context)
+	query := context.Request.URL.Query()
+	w, e := g.workers.pick(newRoomRequest(query))
+	if e != nil {
+		log.WithError(e).Warn("no worker to relay to")
+		if e == errRoomNotFound && query.Get("code") != "" {
+			g.guard.lookupFailed(context.ClientIP())
+		}
+		g.refuse(context, e)
+		return
+	}
+	log = log.WithField("worker", w.addr)
+	upstream, e := dialWorker(w.addr)
+	if e != nil {
+		log.WithError(e).Warn("worker dial failed")
+		g.refuse(context, errNoWorker)
+		return
+	}
+	defer upstream.Close()
+	conn, err := g.wsCreator.Upgrade(context.Writer, context.Request, nil)
+	if err != nil {
+		log.WithError(err).Debug("websocket upgrade failed")
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(game.Config.MaxMessageSize)
+	//the relay goroutine and the guard both write to the client
+	writeLocker := &sync.Mutex{}
+	write := func(data []byte) error {
+		writeLocker.Lock()
+		defer writeLocker.Unlock()
+		if e := conn.SetWriteDeadline(time.Now().Add(game.Config.WriteTimeout)); e != nil {
+			return e
+		}
+		return conn.WriteMessage(websocket.TextMessage, data)
+	}
+	g.guard.attach(guardConn, func(e *protocol.Error) {
+		write([]byte(protocol.Encode(protocol.ActionError, e.Encode())))
+		conn.Close()
+	})
+	query.Set(queryRequestId, context.GetString("requestId"))
+	query.Set(queryRemote, context.ClientIP())
+	if e := upstream.WriteMessage(websocket.TextMessage, []byte(handshakeGame+query.Encode())); e != nil {
+		log.WithError(e).Warn("worker handshake failed")
+		return
+	}
+	log.Debug("session relayed")
+	go func() {
+		defer conn.Close()
+		defer upstream.Close()
+		for {
+			_, data, e := upstream.ReadMessage()
+			if e != nil {
+				return
+			}
+			if e := write(data); e != nil {
+				return
+			}
+		}
+	}()
+	for {
+		_, data, e := conn.ReadMessage()
+		if e != nil {
+			return
+		}
+		if e := upstream.SetWriteDeadline(time.Now().Add(game.Config.WriteTimeout)); e != nil {
+			return
+		}
+		if e := upstream.WriteMessage(websocket.TextMessage, data); e != nil {
+			return
+		}
+	}
+}
+
+func (g *Gateway) listWorkers(c *gin.Context) {
+	if g.workers == nil {
+		c.JSON(http.StatusOK, []gin.H{})
+		return
+	}
+	g.workers.locker.Lock()
+	workers := make([]gin.H, len(g.workers.workers))
+	for i, w := range g.workers.workers {
+		workers[i] = gin.H{
+			"addr":     w.addr,
+			"alive":    w.alive,
+			"lastSeen": w.lastSeen,
+			"players":  w.status.Players,
+			"battles":  w.status.Battles,
+		}
+	}
+	g.workers.locker.Unlock()
+	c.JSON(http.StatusOK, workers)
+}
@@ -0,0 +1,256 @@
+package gateway
+
+import (
+	"bytes"
+	"github.com/gorilla/websocket"
+	"go-agar/internal/game"
+	"go-agar/pkg/client"
+	"go-agar/pkg/protocol"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+//setWorkerConfig makes the gateways created after it relay to addrs
+func setWorkerConfig(t *testing.T, addrs []string) {
+	secret, workers, interval := game.Config.WorkerSecret, game.Config.Workers, game.Config.WorkerPollInterval
+	game.Config.WorkerSecret = "test-secret"
+	game.Config.Workers = addrs
+	game.Config.WorkerPollInterval = 50 * time.Millisecond
+	t.Cleanup(func() {
+		game.Config.WorkerSecret, game.Config.Workers, game.Config.WorkerPollInterval = secret, workers, interval
+	})
+}
+
+//startWorker runs a battle worker on an ephemeral port of localhost
+func startWorker(t *testing.T) (*Gateway, string) {
+	g, e := NewGateway()
+	if e != nil {
+		t.Fatal(e)
+	}
+	listener, e := net.Listen("tcp", "127.0.0.1:0")
+	if e != nil {
+		t.Fatal(e)
+	}
+	done := make(chan error, 1)
+	go func() { done <- g.serveWorker(listener) }()
+	t.Cleanup(func() {
+		listener.Close()
+		<-done
+		waitFor(5*time.Second, func() bool {
+			g.battleLocker.Lock()
+			defer g.battleLocker.Unlock()
+			return len(g.battles) == 0
+		})
+	})
+	return g, listener.Addr().String()
+}
+
+func (r *workerRegistry) allAlive() bool {
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	for _, w := range r.workers {
+		if !w.alive {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *workerRegistry) hasRoomKey(key string) bool {
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	for _, w := range r.workers {
+		for _, room := range w.status.Rooms {
+			if room.CodeKey == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestWorkersPlaceAndJoinByCode(t *testing.T) {
+	setWorkerConfig(t, nil)
+	w1, addr1 := startWorker(t)
+	w2, addr2 := startWorker(t)
+	game.Config.Workers = []string{addr1, addr2}
+	g, addr := startGateway(t)
+	if !waitFor(5*time.Second, g.workers.allAlive) {
+		t.Fatal("workers not up")
+	}
+
+	//joins go to the least loaded worker
+	for i := 0; i < 4; i++ {
+		c, e := dialTest(addr, client.DefaultOptions("p"))
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer c.Close()
+		c.waitSetup(t)
+	}
+	if n1, n2 := w1.sessionCount(), w2.sessionCount(); n1 != 2 || n2 != 2 {
+		t.Fatal("sessions per worker", n1, n2)
+	}
+
+	options := client.DefaultOptions("host")
+	options.Private = true
+	host, e := dialTest(addr, options)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer host.Close()
+	setup := host.waitSetup(t)
+	if setup.RoomCode == "" {
+		t.Fatal("private room without code")
+	}
+	if !waitFor(5*time.Second, func() bool { return g.workers.hasRoomKey(codeKey(setup.RoomCode)) }) {
+		t.Fatal("private room not polled")
+	}
+	for _, a := range []string{addr1, addr2} {
+		status, e := pollWorker(a)
+		if e != nil {
+			t.Fatal(e)
+		}
+		for _, room := range status.Rooms {
+			if room.Code != "" {
+				t.Fatal("worker told the code of room", room.Name)
+			}
+		}
+	}
+
+	options = client.DefaultOptions("guest")
+	options.Code = strings.ToLower(setup.RoomCode)
+	guest, e := dialTest(addr, options)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer guest.Close()
+	if s := guest.waitSetup(t); s.RoomName != setup.RoomName {
+		t.Fatal("joined", s.RoomName, "instead of", setup.RoomName)
+	}
+	found := false
+	for _, w := range []*Gateway{w1, w2} {
+		if b := w.findBattle("", setup.RoomCode); b != nil {
+			found = true
+			if players := b.Info().Players; players != 2 {
+				t.Fatal("players in the private room", players)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("private room not on a worker")
+	}
+
+	options = client.DefaultOptions("lost")
+	options.Code = "NOPE00"
+	lost, e := dialTest(addr, options)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer lost.Close()
+	if e := lost.waitError(t); e.Code != protocol.ErrorRoomNotFound {
+		t.Fatal("error code", e.Code)
+	}
+}
+
+func TestWorkerRequiresSecret(t *testing.T) {
+	setWorkerConfig(t, nil)
+	_, addr := startWorker(t)
+	c, e := net.Dial("tcp", addr)
+	if e != nil {
+		t.Fatal(e)
+	}
+	conn := newFrameConn(c)
+	defer conn.Close()
+	conn.WriteMessage(websocket.TextMessage, []byte("wrong"))
+	conn.WriteMessage(websocket.TextMessage, []byte(handshakeStatus))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, data, e := conn.ReadMessage(); e == nil {
+		t.Fatal("status without secret:", string(data))
+	}
+	if _, e := pollWorker(addr); e != nil {
+		t.Fatal("status with secret:", e)
+	}
+}
+
+func TestNoWorkerRefused(t *testing.T) {
+	listener, e := net.Listen("tcp", "127.0.0.1:0")
+	if e != nil {
+		t.Fatal(e)
+	}
+	//nothing listens there anymore
+	listener.Close()
+	setWorkerConfig(t, []string{listener.Addr().String()})
+	_, addr := startGateway(t)
+	c, e := dialTest(addr, client.DefaultOptions("p"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer c.Close()
+	if e := c.waitError(t); e.Code != protocol.ErrorUnavailable {
+		t.Fatal("error code", e.Code)
+	}
+}
+
+func TestPickWorker(t *testing.T) {
+	setWorkerConfig(t, nil)
+	r := newWorkerRegistry([]string{"a", "b", "c"})
+	r.workers[0].alive, r.workers[0].status.Players = true, 5
+	r.workers[1].alive, r.workers[1].status.Players = true, 3
+	r.workers[2].status.Players = 0
+	r.workers[0].status.Rooms = []*workerRoom{{BattleInfo: game.BattleInfo{Id: "open"}}}
+	r.workers[1].status.Rooms = []*workerRoom{{BattleInfo: game.BattleInfo{Id: "secret", Private: true}, CodeKey: codeKey("ABC123")}}
+
+	//the dead worker is skipped, and a pick counts until the next poll
+	for _, want := range []string{"b", "b", "a", "b"} {
+		if w, _ := r.pick(&roomRequest{}); w == nil || w.addr != want {
+			t.Fatal("picked", w, "instead of", want)
+		}
+	}
+	if w, _ := r.pick(&roomRequest{id: "open"}); w == nil || w.addr != "a" {
+		t.Fatal("room by id on", w)
+	}
+	if w, _ := r.pick(&roomRequest{code: "abc123"}); w == nil || w.addr != "b" {
+		t.Fatal("room by code on", w)
+	}
+	if _, e := r.pick(&roomRequest{id: "secret"}); e != errRoomNotFound {
+		t.Fatal("private room joined by id", e)
+	}
+	for _, w := range r.workers {
+		w.alive = false
+	}
+	if _, e := r.pick(&roomRequest{}); e != errNoWorker {
+		t.Fatal("picked a dead worker", e)
+	}
+}
+
+func TestFrameConn(t *testing.T) {
+	a, b := net.Pipe()
+	ca, cb := newFrameConn(a), newFrameConn(b)
+	defer ca.Close()
+	defer cb.Close()
+	messages := [][]byte{[]byte("05|1,2,3"), {}, bytes.Repeat([]byte("x"), 70000)}
+	go func() {
+		for _, m := range messages {
+			ca.WriteMessage(websocket.TextMessage, m)
+		}
+	}()
+	for _, m := range messages {
+		_, data, e := cb.ReadMessage()
+		if e != nil {
+			t.Fatal(e)
+		}
+		if !bytes.Equal(data, m) {
+			t.Fatal("frame changed on the way, size", len(data))
+		}
+	}
+	if e := ca.WriteMessage(websocket.TextMessage, make([]byte, maxFrameSize+1)); e != errFrameTooLarge {
+		t.Fatal("large frame written", e)
+	}
+	go a.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	if _, _, e := cb.ReadMessage(); e != errFrameTooLarge {
+		t.Fatal("large frame read", e)
+	}
+}
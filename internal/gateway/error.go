@@ -0,0 +1,30 @@
+package gateway
+
+import (
+	"go-agar/internal/game"
+	"go-agar/pkg/protocol"
+)
+
+var errorCodes = map[error]protocol.ErrorCode{
+	game.ErrBattleFull:    protocol.ErrorBattleFull,
+	game.ErrBattleClosed:  protocol.ErrorShuttingDown,
+	game.ErrNameTaken:     protocol.ErrorNameTaken,
+	errInvalidName:        protocol.ErrorInvalidName,
+	errRoomNotFound:       protocol.ErrorRoomNotFound,
+	errBanned:             protocol.ErrorBanned,
+	errTooManyConnections: protocol.ErrorRateLimited,
+	errTooManyLookups:     protocol.ErrorRateLimited,
+	errTooManyRooms:       protocol.ErrorRateLimited,
+	errNoWorker:           protocol.ErrorUnavailable,
+}
+
+//clientError tells the client what went wrong without leaking internal errors
+func clientError(e error) *protocol.Error {
+	if pe, ok := e.(*protocol.Error); ok {
+		return pe
+	}
+	if code, ok := errorCodes[e]; ok {
+		return protocol.NewError(code, e.Error())
+	}
+	return protocol.NewError(protocol.ErrorUnknown, "")
+}
@@ -0,0 +1,73 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"github.com/gorilla/websocket"
+	"io"
+	"net"
+	"time"
+)
+
+//frames larger than this are treated as a broken stream
+const maxFrameSize = 1 << 20
+
+var errFrameTooLarge = errors.New("frame too large")
+
+//frameConn carries websocket messages between the gateway and a worker,
+//each frame is a 4 bytes big endian length followed by the message
+type frameConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newFrameConn(conn net.Conn) *frameConn {
+	return &frameConn{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+	}
+}
+
+func (c *frameConn) ReadMessage() (int, []byte, error) {
+	var header [4]byte
+	if _, e := io.ReadFull(c.reader, header[:]); e != nil {
+		return 0, nil, e
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxFrameSize {
+		return 0, nil, errFrameTooLarge
+	}
+	data := make([]byte, size)
+	if _, e := io.ReadFull(c.reader, data); e != nil {
+		return 0, nil, e
+	}
+	return websocket.TextMessage, data, nil
+}
+
+func (c *frameConn) WriteMessage(messageType int, data []byte) error {
+	if len(data) > maxFrameSize {
+		return errFrameTooLarge
+	}
+	frame := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(frame, uint32(len(data)))
+	copy(frame[4:], data)
+	_, e := c.conn.Write(frame)
+	return e
+}
+
+func (c *frameConn) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+func (c *frameConn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *frameConn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+func (c *frameConn) Close() error {
+	return c.conn.Close()
+}
@@ -1,45 +1,90 @@
 package gateway
 
 import (
+	"crypto/subtle"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
 	"go-agar/internal/asset"
 	"go-agar/internal/game"
+	"go-agar/internal/util"
+	"go-agar/pkg/protocol"
+	"go-agar/web"
+	"math"
 	"net/http"
+	"net/url"
+	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
-const (
-	ActionError        = "00"
-	ActionPing         = "01"
-	ActionGameSetup    = "02"
-	ActionChat         = "03"
-	ActionPlayerStatus = "04"
-	ActionMove         = "05"
-	ActionFire         = "06"
-	ActionSplit        = "07"
-	ActionLeaderBoard  = "08"
+//Conn is what a session talks through, a websocket or a relayed connection from the gateway
+type Conn interface {
+	ReadMessage() (messageType int, p []byte, err error)
+	WriteMessage(messageType int, data []byte) error
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+	Close() error
+}
+
+var (
+	errRoomNotFound  = errors.New("room not found")
+	errSessionClosed = errors.New("session closed")
 )
 
+type roomRequest struct {
+	id      string
+	code    string
+	create  string
+	private bool
+}
+
+func newRoomRequest(query url.Values) *roomRequest {
+	return &roomRequest{
+		id:      query.Get("room"),
+		code:    query.Get("code"),
+		create:  query.Get("create"),
+		private: query.Get("private") == "1",
+	}
+}
+
 type Gateway struct {
-	wsCreator      *websocket.Upgrader
+	wsCreator *websocket.Upgrader
+	//sessionBattles holds every open session, the battle is nil while a dead one waits to respawn in another battle
 	sessionBattles map[*Session]*game.Battle
 	battles        []*game.Battle
 	battleLocker   *sync.Mutex
+	workers        *workerRegistry
+	guard          *guard
+	skins          *skinRegistry
+	log            *logrus.Entry
+	//roomCreators holds the ip that opened each running room, battles opened by matchmaking are not in it
+	roomCreators map[*game.Battle]string
 }
 
 func NewGateway() (*Gateway, error) {
-	return &Gateway{
-		wsCreator: &websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true
-			},
-		},
+	g := &Gateway{
 		sessionBattles: make(map[*Session]*game.Battle),
+		roomCreators:   make(map[*game.Battle]string),
 		battleLocker:   &sync.Mutex{},
-	}, nil
+		guard:          newGuard(),
+		skins:          newSkinRegistry(game.Config.SkinDir),
+		log:            game.Log.WithField("component", "gateway"),
+	}
+	g.wsCreator = &websocket.Upgrader{
+		CheckOrigin: g.checkOrigin,
+	}
+	if len(game.Config.Workers) > 0 {
+		if game.Config.WorkerSecret == "" {
+			return nil, errNoWorkerSecret
+		}
+		g.workers = newWorkerRegistry(game.Config.Workers)
+	}
+	return g, nil
 }
 
 func (g *Gateway) Run() {
@@ -47,64 +92,150 @@ func (g *Gateway) Run() {
 	if !game.Config.Debug {
 		gin.SetMode(gin.ReleaseMode)
 	}
-	engine := gin.Default()
-	engine.GET("/game", func(c *gin.Context) {
-		go g.openSession(c)
-		c.Done()
-	})
-	engine.GET("/", func(c *gin.Context) {
-		bytes := asset.MustAsset("web/index.html")
-		c.Data(http.StatusOK, "text/html", bytes)
-	})
-	engine.GET("/game.js", func(c *gin.Context) {
-		bytes := asset.MustAsset("web/game.js")
-		c.Data(http.StatusOK, "application/x-javascript", bytes)
+	engine := gin.New()
+	engine.ForwardedByClientIP = game.Config.TrustProxyHeaders
+	engine.Use(g.logRequest, gin.Recovery())
+	if g.workers != nil {
+		go g.workers.watch()
+		defer g.workers.stop()
+	}
+	if game.Config.AdminToken != "" {
+		admin := engine.Group("/admin", g.checkAdmin)
+		admin.GET("/sessions", g.listSessions)
+		admin.GET("/workers", g.listWorkers)
+		admin.GET("/bans", g.listBans)
+		admin.POST("/bans", g.addBan)
+		admin.DELETE("/bans", g.removeBan)
+	}
+	engine.GET("/game", g.openSession)
+	engine.GET("/rooms", g.listRooms)
+	engine.GET("/looks", g.listLooks)
+	engine.GET("/skins/*file", g.skins.serve)
+	assets := g.newAssetServer()
+	engine.NoRoute(gin.WrapH(assets))
+	if e := g.listen(engine); e != nil {
+		g.log.WithError(e).Error("server stop")
+	}
+}
+
+//logRequest tags every request with an id, which is also carried by the logs of its session
+func (g *Gateway) logRequest(c *gin.Context) {
+	id := c.GetHeader("X-Request-Id")
+	if id == "" {
+		id = util.GenId()
+	}
+	c.Set("requestId", id)
+	c.Header("X-Request-Id", id)
+	start := time.Now()
+	c.Next()
+	entry := g.requestLog(c).WithFields(logrus.Fields{
+		"method":  c.Request.Method,
+		"path":    c.Request.URL.Path,
+		"status":  c.Writer.Status(),
+		"latency": time.Since(start).String(),
 	})
-	engine.GET("/game.css", func(c *gin.Context) {
-		bytes := asset.MustAsset("web/game.css")
-		c.Data(http.StatusOK, "text/css", bytes)
+	if c.Writer.Status() >= http.StatusInternalServerError {
+		entry.Warn("request")
+	} else {
+		entry.Debug("request")
+	}
+}
+
+func (g *Gateway) requestLog(c *gin.Context) *logrus.Entry {
+	return g.log.WithFields(logrus.Fields{
+		"requestId": c.GetString("requestId"),
+		"remote":    c.ClientIP(),
 	})
-	println("server start on port [", game.Config.Port, "]")
-	engine.Run(":" + strconv.Itoa(game.Config.Port))
 }
 
 func (g *Gateway) Stop() {
+	g.battleLocker.Lock()
+	defer g.battleLocker.Unlock()
 	for _, b := range g.battles {
 		b.Stop()
 	}
 }
 
 func (g *Gateway) openSession(context *gin.Context) {
+	guardConn := g.admitSession(context)
+	if guardConn == nil {
+		return
+	}
+	defer g.guard.release(guardConn)
+	if g.workers != nil {
+		g.relaySession(context, guardConn)
+		return
+	}
 	conn, err := g.wsCreator.Upgrade(context.Writer, context.Request, nil)
 	if err != nil {
+		g.requestLog(context).WithError(err).Debug("websocket upgrade failed")
 		return
 	}
+	conn.SetReadLimit(game.Config.MaxMessageSize)
+	g.serveSession(conn, context.ClientIP(), context.Request.URL.Query(), g.requestLog(context), guardConn)
+}
+
+//serveSession runs the session until the connection closes, guardConn is nil on workers
+func (g *Gateway) serveSession(conn Conn, remote string, query url.Values, log *logrus.Entry, guardConn *guardConn) {
 	defer conn.Close()
-	session := NewSession(context.Query("name"), conn)
+	name, nameErr := normalizePlayerName(query.Get("name"))
+	session := NewSession(name, remote, conn, log)
+	session.color = g.skins.color(query.Get("color"))
+	session.skin = g.skins.skin(query.Get("skin"))
+	defer session.wait()
 	defer g.closeSession(session)
-	g.allocationBattle(session)
+	if guardConn != nil && !g.guard.attach(guardConn, session.shut) {
+		return
+	}
+	session.log.Info("session open")
+	if v := query.Get("v"); v != "" && v != strconv.Itoa(protocol.Version) {
+		session.reject(protocol.NewError(protocol.ErrorVersionMismatch, "server speaks protocol version "+strconv.Itoa(protocol.Version)))
+		return
+	}
+	if nameErr != nil {
+		session.reject(nameErr)
+		return
+	}
+	if e := g.allocationBattle(session, newRoomRequest(query)); e != nil {
+		session.log.WithFields(logrus.Fields{"room": query.Get("room"), "code": query.Get("code")}).Warn("no battle to join")
+		if e == errRoomNotFound && guardConn != nil && query.Get("code") != "" {
+			g.guard.lookupFailed(remote)
+		}
+		session.reject(e)
+		return
+	}
+	limiter := newRateLimiter(game.Config.MaxMessagesPerSecond, time.Second)
 	for {
 		if e := conn.SetReadDeadline(time.Now().Add(game.Config.MaxHeartbeatInterval)); e != nil {
 			return
 		}
 		_, bytes, e := conn.ReadMessage()
 		if e != nil {
+			session.logReadError(e)
+			return
+		}
+		if !limiter.allow() {
+			session.reject(protocol.NewError(protocol.ErrorRateLimited, ""))
 			return
 		}
-		msg := string(bytes)
-		msgType := msg[:2]
+		msgType, payload, ok := protocol.Decode(string(bytes))
+		if !ok {
+			continue
+		}
 		switch msgType {
-		case ActionError:
-		case ActionPing:
-			session.ping()
-		case ActionChat:
-			session.say(msg[3:])
-		case ActionMove:
-			session.move(conn, msg[3:])
-		case ActionFire:
+		case protocol.ActionError:
+		case protocol.ActionPing:
+			session.ping(payload)
+		case protocol.ActionChat:
+			session.say(payload)
+		case protocol.ActionMove:
+			session.move(payload)
+		case protocol.ActionFire:
 			session.fire()
-		case ActionSplit:
+		case protocol.ActionSplit:
 			session.split()
+		case protocol.ActionRespawn:
+			g.respawnSession(session)
 		}
 	}
 }
@@ -115,6 +246,9 @@ func (g *Gateway) closeSession(s *Session) {
 	b := g.sessionBattles[s]
 	delete(g.sessionBattles, s)
 	g.battleLocker.Unlock()
+	if b == nil {
+		return
+	}
 	for {
 		select {
 		case msg := <-s.broadcast:
@@ -125,66 +259,333 @@ func (g *Gateway) closeSession(s *Session) {
 	}
 }
 
-func (g *Gateway) allocationBattle(s *Session) {
+//releaseSession forgets the battle of a session without a live player in it but keeps the session for shutdown and the admin api,
+//a dead one waiting to respawn is matched to another battle by respawnSession
+func (g *Gateway) releaseSession(s *Session, b *game.Battle) {
 	g.battleLocker.Lock()
 	defer g.battleLocker.Unlock()
-	if _, ok := g.sessionBattles[s]; ok {
+	if g.sessionBattles[s] == b {
+		g.sessionBattles[s] = nil
+	}
+}
+
+//allocationBattle picks the battle under battleLocker but joins it without, AddPlayer waits for a tick of the battle
+func (g *Gateway) allocationBattle(s *Session, req *roomRequest) error {
+	g.battleLocker.Lock()
+	joined := g.sessionBattles[s] != nil
+	g.battleLocker.Unlock()
+	if joined {
+		return nil
+	}
+	if req.create != "" || req.private {
+		b, e := g.createRoom(s.remote, normalizeRoomName(req.create), req.private)
+		if e != nil {
+			return e
+		}
+		return g.joinBattle(s, b)
+	}
+	if req.id != "" || req.code != "" {
+		b := g.findBattle(req.id, strings.ToUpper(req.code))
+		if b == nil {
+			return errRoomNotFound
+		}
+		//the room was reaped while joining, the server is fine
+		if e := g.joinBattle(s, b); e != game.ErrBattleClosed {
+			return e
+		}
+		return errRoomNotFound
+	}
+	mass := game.Config.DefaultPlayerMass
+	if _, p := s.current(); p != nil {
+		mass = p.View().MassTotal
+	}
+	for {
+		g.battleLocker.Lock()
+		battles := g.matchBattles(mass)
+		g.battleLocker.Unlock()
+		taken := false
+		for _, b := range battles {
+			e := g.joinBattle(s, b)
+			if e == nil {
+				return nil
+			}
+			taken = taken || e == game.ErrNameTaken
+		}
+		//with DuplicateNames reject the name is refused rather than moved to a battle of its own
+		if taken {
+			return game.ErrNameTaken
+		}
+		//sessions joining meanwhile may fill the new battle first, or it may be reaped, then it is matched again
+		if e := g.joinBattle(s, g.newBattle("", false)); e != game.ErrBattleFull && e != game.ErrBattleClosed {
+			return e
+		}
+	}
+}
+
+func (g *Gateway) findBattle(id string, code string) *game.Battle {
+	g.battleLocker.Lock()
+	defer g.battleLocker.Unlock()
+	for _, b := range g.battles {
+		if (id != "" && !b.Private && b.Id == id) || (code != "" && b.Code == code) {
+			return b
+		}
+	}
+	return nil
+}
+
+func (g *Gateway) newBattle(name string, private bool) *game.Battle {
+	b := game.NewBattle(name, private)
+	g.battleLocker.Lock()
+	g.battles = append(g.battles, b)
+	g.battleLocker.Unlock()
+	g.mount(b)
+	return b
+}
+
+//createRoom opens a room asked for by a client unless MaxRooms or MaxRoomsPerIP are reached
+func (g *Gateway) createRoom(ip string, name string, private bool) (*game.Battle, error) {
+	g.battleLocker.Lock()
+	own := 0
+	for _, creator := range g.roomCreators {
+		if creator == ip {
+			own++
+		}
+	}
+	if (game.Config.MaxRooms > 0 && len(g.roomCreators) >= game.Config.MaxRooms) ||
+		(game.Config.MaxRoomsPerIP > 0 && own >= game.Config.MaxRoomsPerIP) {
+		g.battleLocker.Unlock()
+		return nil, errTooManyRooms
+	}
+	b := game.NewBattle(name, private)
+	g.battles = append(g.battles, b)
+	g.roomCreators[b] = ip
+	g.battleLocker.Unlock()
+	g.mount(b)
+	return b, nil
+}
+
+func (g *Gateway) mount(b *game.Battle) {
+	//the battle closes events when it stops
+	events, _ := b.Subscribe(game.Config.EventQueueSize, handledEvent)
+	go g.mountBattle(b, events)
+}
+
+//respawnSession puts a new player of a dead session back in its battle, or matches another one if that is full or gone.
+//A request sent before RespawnDelay has passed is carried out once it has, one sent while playing gets the setup again
+func (g *Gateway) respawnSession(s *Session) {
+	b, wait := s.respawnable()
+	if wait > 0 {
+		time.AfterFunc(wait, func() {
+			select {
+			case <-s.done:
+			default:
+				g.respawnSession(s)
+			}
+		})
+		return
+	}
+	if b == nil {
+		if b, _ := s.current(); b != nil && s.playing(b) {
+			s.sendSetup(b)
+		}
+		return
+	}
+	e := g.joinBattle(s, b)
+	if e == nil {
+		s.log.Debug("player respawned")
 		return
 	}
+	g.releaseSession(s, b)
+	s.log.WithError(e).Info("respawn in another battle")
+	if e := g.allocationBattle(s, &roomRequest{}); e != nil {
+		s.reject(e)
+	}
+}
+
+//joinBattle must be called without battleLocker, which is only taken to record the battle of the session
+func (g *Gateway) joinBattle(s *Session, b *game.Battle) error {
+	if e := s.join(b); e != nil {
+		return e
+	}
+	g.battleLocker.Lock()
+	g.sessionBattles[s] = b
+	g.battleLocker.Unlock()
+	return nil
+}
+
+//matchBattles returns the public battles with space, the emptier and the closer in mass the earlier, battleLocker must be held
+func (g *Gateway) matchBattles(mass float64) []*game.Battle {
+	var battles []*game.Battle
+	scores := make(map[*game.Battle]float64)
 	for _, b := range g.battles {
-		if s.join(b) {
-			g.sessionBattles[s] = b
+		info := b.Info()
+		if b.Private || info.Players >= info.Limit {
+			continue
+		}
+		score := float64(info.Players) / float64(info.Limit)
+		if info.AverageMass > 0 && mass > 0 {
+			score += math.Abs(math.Log(info.AverageMass/mass)) * game.Config.MatchMassWeight
+		}
+		scores[b] = score
+		battles = append(battles, b)
+	}
+	sort.SliceStable(battles, func(i, j int) bool {
+		return scores[battles[i]] < scores[battles[j]]
+	})
+	return battles
+}
+
+func (g *Gateway) removeBattle(b *game.Battle) {
+	g.battleLocker.Lock()
+	defer g.battleLocker.Unlock()
+	delete(g.roomCreators, b)
+	for i, b2 := range g.battles {
+		if b2 == b {
+			g.battles = append(g.battles[:i], g.battles[i+1:]...)
 			return
 		}
 	}
-	b := game.NewBattle()
-	g.battles = append(g.battles, b)
-	go g.mountBattle(b)
-	if s.join(b) {
-		g.sessionBattles[s] = b
-	} else {
-		g.closeSession(s)
+}
+
+func (g *Gateway) battleSessions(b *game.Battle) []*Session {
+	g.battleLocker.Lock()
+	defer g.battleLocker.Unlock()
+	var sessions []*Session
+	for s, b2 := range g.sessionBattles {
+		if b2 == b {
+			sessions = append(sessions, s)
+		}
 	}
+	return sessions
 }
 
-func (g *Gateway) mountBattle(b *game.Battle) {
+func (g *Gateway) mountBattle(b *game.Battle, events <-chan game.Event) {
 	leaderBoardTicker := time.NewTicker(time.Second)
 	defer leaderBoardTicker.Stop()
+	pingTicker := time.NewTicker(game.Config.PingInterval)
+	defer pingTicker.Stop()
 	for {
 		select {
 		case _, ok := <-b.Tick:
 			if !ok {
-				for s, b2 := range g.sessionBattles {
-					if b2 == b {
+				g.removeBattle(b)
+				sessions := g.battleSessions(b)
+				g.log.WithFields(logrus.Fields{"battle": b.Id, "sessions": len(sessions)}).Debug("battle unmounted")
+				for _, s := range sessions {
+					if s.playing(b) {
 						g.closeSession(s)
+					} else {
+						g.releaseSession(s, b)
 					}
 				}
 				return
 			}
-			for s, b2 := range g.sessionBattles {
-				if b2 == b {
-					s.pushPlayerStatus()
-					select {
-					case c := <-s.broadcast:
-						g.broadcast(b, c)
-					default:
-					}
+			sessions := g.battleSessions(b)
+			util.Parallel(len(sessions), game.Config.TickWorkers, func(i int) {
+				sessions[i].pushPlayerStatus()
+			})
+			for _, s := range sessions {
+				select {
+				case c := <-s.broadcast:
+					g.broadcast(b, c)
+				default:
 				}
 			}
+		case e, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			g.handleEvent(b, e)
 		case <-leaderBoardTicker.C:
-			for s, b2 := range g.sessionBattles {
-				if b2 == b {
-					s.pushLeaderBoard()
-				}
+			for _, s := range g.battleSessions(b) {
+				s.pushLeaderBoard()
+			}
+		case <-pingTicker.C:
+			for _, s := range g.battleSessions(b) {
+				s.pingClient()
 			}
 		}
 	}
 }
 
-func (g *Gateway) broadcast(b *game.Battle, c *Chat) {
-	for s, b2 := range g.sessionBattles {
-		if b2 == b {
-			s.send(ActionChat, c.Type+c.Data)
+//handledEvent keeps the events handleEvent does something with, achievements reach players with their view
+func handledEvent(e game.Event) bool {
+	eaten, ok := e.(*game.PlayerEaten)
+	return ok && eaten.Killed
+}
+
+//handleEvent pushes what the players of the battle should know about
+func (g *Gateway) handleEvent(b *game.Battle, e game.Event) {
+	switch e := e.(type) {
+	case *game.PlayerEaten:
+		if !e.Killed {
+			return
 		}
+		kill := (&protocol.Kill{Killer: e.Eater, Victim: e.Victim}).Encode()
+		for _, s := range g.battleSessions(b) {
+			s.send(protocol.ActionKill, kill)
+		}
+	}
+}
+
+func (g *Gateway) broadcast(b *game.Battle, c *protocol.Chat) {
+	for _, s := range g.battleSessions(b) {
+		s.send(protocol.ActionChat, c.Encode())
+	}
+}
+
+func (g *Gateway) checkAdmin(c *gin.Context) {
+	token := c.GetHeader("X-Admin-Token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(game.Config.AdminToken)) != 1 {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	c.Next()
+}
+
+func (g *Gateway) listSessions(c *gin.Context) {
+	g.battleLocker.Lock()
+	sessions := make([]gin.H, 0, len(g.sessionBattles))
+	for s, b := range g.sessionBattles {
+		battle := ""
+		if b != nil {
+			battle = b.Id
+		}
+		sessions = append(sessions, gin.H{
+			"id":        s.id,
+			"name":      s.name,
+			"battle":    battle,
+			"remote":    s.remote,
+			"latencyMs": float64(s.Latency()) / float64(time.Millisecond),
+			"play":      s.summary(),
+		})
+	}
+	g.battleLocker.Unlock()
+	c.JSON(http.StatusOK, sessions)
+}
+
+func (g *Gateway) listRooms(c *gin.Context) {
+	if g.workers != nil {
+		c.JSON(http.StatusOK, g.workers.rooms())
+		return
+	}
+	g.battleLocker.Lock()
+	rooms := make([]*game.BattleInfo, 0, len(g.battles))
+	for _, b := range g.battles {
+		if !b.Private {
+			rooms = append(rooms, b.Info())
+		}
+	}
+	g.battleLocker.Unlock()
+	c.JSON(http.StatusOK, rooms)
+}
+
+//newAssetServer serves the web client embedded in the binary, or AssetDir while developing
+func (g *Gateway) newAssetServer() *asset.Server {
+	if game.Config.AssetDir != "" {
+		g.log.WithField("dir", game.Config.AssetDir).Info("serving assets from disk")
+		return asset.NewServer(os.DirFS(game.Config.AssetDir), true, 0)
 	}
+	return asset.NewServer(web.Static(), false, game.Config.AssetMaxAge)
 }
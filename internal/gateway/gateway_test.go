@@ -0,0 +1,468 @@
+package gateway
+
+import (
+	"github.com/gin-gonic/gin"
+	"go-agar/internal/game"
+	"go-agar/pkg/client"
+	"go-agar/pkg/protocol"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//testClient keeps what a client got from the server
+type testClient struct {
+	*client.Client
+	setup  chan *protocol.GameSetup
+	status chan *protocol.PlayerStatus
+	chat   chan *protocol.Chat
+	err    chan *protocol.Error
+}
+
+func dialTest(addr string, options *client.Options) (*testClient, error) {
+	c := &testClient{
+		setup:  make(chan *protocol.GameSetup, 4),
+		status: make(chan *protocol.PlayerStatus, 1),
+		chat:   make(chan *protocol.Chat, 64),
+		err:    make(chan *protocol.Error, 1),
+	}
+	var e error
+	c.Client, e = client.Dial(addr, options, &client.Handler{
+		GameSetup: func(s *protocol.GameSetup) { c.setup <- s },
+		PlayerStatus: func(s *protocol.PlayerStatus) {
+			select {
+			case <-c.status:
+			default:
+			}
+			c.status <- s
+		},
+		Chat: func(m *protocol.Chat) {
+			select {
+			case c.chat <- m:
+			default:
+			}
+		},
+		Error: func(e *protocol.Error) { c.err <- e },
+	})
+	return c, e
+}
+
+func (c *testClient) waitSetup(t *testing.T) *protocol.GameSetup {
+	t.Helper()
+	select {
+	case s := <-c.setup:
+		return s
+	case e := <-c.err:
+		t.Fatal("refused:", e.Code, e.Message)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no game setup")
+	}
+	return nil
+}
+
+func (c *testClient) waitError(t *testing.T) *protocol.Error {
+	t.Helper()
+	select {
+	case e := <-c.err:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("no error")
+	}
+	return nil
+}
+
+//startGateway serves the game endpoint of a new gateway, battles run in this process unless Workers is set
+func startGateway(t *testing.T) (*Gateway, string) {
+	gin.SetMode(gin.TestMode)
+	g, e := NewGateway()
+	if e != nil {
+		t.Fatal(e)
+	}
+	engine := gin.New()
+	engine.GET("/game", g.openSession)
+	server := httptest.NewServer(engine)
+	if g.workers != nil {
+		go g.workers.watch()
+	}
+	t.Cleanup(func() {
+		server.Close()
+		if g.workers != nil {
+			g.workers.stop()
+		}
+		g.Stop()
+		//battles read the config until they are gone
+		waitFor(5*time.Second, func() bool {
+			g.battleLocker.Lock()
+			defer g.battleLocker.Unlock()
+			return len(g.battles) == 0
+		})
+	})
+	return g, "ws" + strings.TrimPrefix(server.URL, "http") + "/game"
+}
+
+func waitFor(timeout time.Duration, cond func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
+}
+
+func (g *Gateway) sessionCount() int {
+	g.battleLocker.Lock()
+	defer g.battleLocker.Unlock()
+	return len(g.sessionBattles)
+}
+
+//setConfig changes a config value for the test and restores it after
+func setConfig(t *testing.T, field *int, value int) {
+	old := *field
+	*field = value
+	t.Cleanup(func() { *field = old })
+}
+
+func TestSessionsJoinConcurrently(t *testing.T) {
+	setConfig(t, &game.Config.BattlePlayerLimit, 3)
+	g, addr := startGateway(t)
+	const clients = 10
+	wg := &sync.WaitGroup{}
+	conns := make([]*testClient, clients)
+	for i := range conns {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c, e := dialTest(addr, client.DefaultOptions("p"))
+			if e != nil {
+				t.Error(e)
+				return
+			}
+			conns[i] = c
+		}(i)
+	}
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+	for _, c := range conns {
+		c.waitSetup(t)
+		defer c.Close()
+	}
+	if n := g.sessionCount(); n != clients {
+		t.Fatal("sessions", n)
+	}
+	g.battleLocker.Lock()
+	battles := append([]*game.Battle(nil), g.battles...)
+	g.battleLocker.Unlock()
+	players := 0
+	for _, b := range battles {
+		info := b.Info()
+		if info.Players > info.Limit {
+			t.Error("battle over its limit", info.Players)
+		}
+		players += info.Players
+	}
+	if players != clients {
+		t.Error("players in battles", players)
+	}
+
+	for _, c := range conns {
+		wg.Add(1)
+		go func(c *testClient) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				c.Move(10, -10)
+				if i%10 == 0 {
+					c.Fire()
+					c.Split()
+				}
+			}
+			c.Say("hello")
+		}(c)
+	}
+	wg.Wait()
+	for _, c := range conns {
+		select {
+		case <-c.status:
+		case <-time.After(5 * time.Second):
+			t.Fatal("no status")
+		}
+	}
+	for _, c := range conns {
+		c.Close()
+	}
+	if !waitFor(5*time.Second, func() bool { return g.sessionCount() == 0 }) {
+		t.Fatal("sessions left", g.sessionCount())
+	}
+}
+
+func TestSessionRejected(t *testing.T) {
+	_, addr := startGateway(t)
+	options := client.DefaultOptions("admin")
+	c, e := dialTest(addr, options)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer c.Close()
+	if e := c.waitError(t); e.Code != protocol.ErrorInvalidName {
+		t.Fatal("error code", e.Code)
+	}
+	select {
+	case <-c.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection left open")
+	}
+}
+
+//with DuplicateNames reject a matched duplicate is refused instead of getting a battle of its own
+func TestDuplicateNameRejected(t *testing.T) {
+	mode := game.Config.DuplicateNames
+	game.Config.DuplicateNames = game.DuplicateNamesReject
+	t.Cleanup(func() { game.Config.DuplicateNames = mode })
+	g, addr := startGateway(t)
+	first, e := dialTest(addr, client.DefaultOptions("bob"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer first.Close()
+	first.waitSetup(t)
+	second, e := dialTest(addr, client.DefaultOptions("BOB"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer second.Close()
+	if e := second.waitError(t); e.Code != protocol.ErrorNameTaken {
+		t.Fatal("error code", e.Code)
+	}
+	g.battleLocker.Lock()
+	battles := len(g.battles)
+	g.battleLocker.Unlock()
+	if battles != 1 {
+		t.Fatal("battles", battles)
+	}
+}
+
+//a room reaped just as it is joined is reported as gone, not as the server shutting down
+func TestJoinReapedRoom(t *testing.T) {
+	g, addr := startGateway(t)
+	b := g.newBattle("", false)
+	b.Stop()
+	if !waitFor(5*time.Second, func() bool { return g.findBattle(b.Id, "") == nil }) {
+		t.Fatal("battle not removed")
+	}
+	//put it back as if it was found right before it stopped
+	g.battleLocker.Lock()
+	g.battles = append(g.battles, b)
+	g.battleLocker.Unlock()
+	defer g.removeBattle(b)
+	options := client.DefaultOptions("p")
+	options.Room = b.Id
+	c, e := dialTest(addr, options)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer c.Close()
+	if e := c.waitError(t); e.Code != protocol.ErrorRoomNotFound {
+		t.Fatal("error code", e.Code)
+	}
+}
+
+func TestIdleBattlesReaped(t *testing.T) {
+	idle := game.Config.BattleIdleTimeout
+	game.Config.BattleIdleTimeout = time.Millisecond
+	t.Cleanup(func() { game.Config.BattleIdleTimeout = idle })
+	setConfig(t, &game.Config.MaxRoomsPerIP, 0)
+	g, addr := startGateway(t)
+	baseline := runtime.NumGoroutine()
+
+	const battles = 30
+	wg := &sync.WaitGroup{}
+	for i := 0; i < battles; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			options := client.DefaultOptions("p")
+			options.Create = "room " + strconv.Itoa(i)
+			c, e := dialTest(addr, options)
+			if e != nil {
+				t.Error(e)
+				return
+			}
+			defer c.Close()
+			select {
+			case <-c.setup:
+			case <-time.After(5 * time.Second):
+				t.Error("no game setup")
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if !waitFor(10*time.Second, func() bool {
+		g.battleLocker.Lock()
+		defer g.battleLocker.Unlock()
+		return len(g.battles) == 0 && len(g.sessionBattles) == 0
+	}) {
+		t.Fatal("battles left", len(g.battles))
+	}
+	if !waitFor(5*time.Second, func() bool { return runtime.NumGoroutine() <= baseline }) {
+		buf := make([]byte, 1<<20)
+		t.Fatalf("goroutines %d, baseline %d\n%s", runtime.NumGoroutine(), baseline, buf[:runtime.Stack(buf, true)])
+	}
+}
+
+//killSession makes the player of the only session die, as pushPlayerStatus would record it
+func killSession(g *Gateway) (*Session, *game.Battle, *game.Player) {
+	var s *Session
+	var b *game.Battle
+	g.battleLocker.Lock()
+	for s, b = range g.sessionBattles {
+	}
+	g.battleLocker.Unlock()
+	s.playerLocker.Lock()
+	p := s.player
+	s.dead = true
+	s.diedAt = time.Now()
+	s.playerLocker.Unlock()
+	b.RemovePlayer(p)
+	return s, b, p
+}
+
+//a dead player waiting to respawn does not keep its battle, the session outlives it and respawns in another one
+func TestRespawnAfterIdleBattle(t *testing.T) {
+	idle, delay := game.Config.BattleIdleTimeout, game.Config.RespawnDelay
+	game.Config.BattleIdleTimeout, game.Config.RespawnDelay = 50*time.Millisecond, 0
+	t.Cleanup(func() { game.Config.BattleIdleTimeout, game.Config.RespawnDelay = idle, delay })
+	g, addr := startGateway(t)
+	c, e := dialTest(addr, client.DefaultOptions("p"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer c.Close()
+	c.waitSetup(t)
+
+	s, b, _ := killSession(g)
+	if !waitFor(5*time.Second, func() bool { return g.findBattle(b.Id, "") == nil }) {
+		t.Fatal("idle battle not stopped")
+	}
+	select {
+	case <-s.done:
+		t.Fatal("dead session closed with its battle")
+	default:
+	}
+	if n := g.sessionCount(); n != 1 {
+		t.Fatal("released session not tracked")
+	}
+
+	if e := c.Respawn(); e != nil {
+		t.Fatal(e)
+	}
+	c.waitSetup(t)
+	if current, _ := s.current(); current == b || g.findBattle(current.Id, "") == nil {
+		t.Fatal("not respawned in a running battle")
+	}
+	if n := g.sessionCount(); n != 1 {
+		t.Fatal("sessions", n)
+	}
+}
+
+//a respawn sent during RespawnDelay is carried out once the delay has passed, one sent while playing is answered with the setup
+func TestEarlyRespawnQueued(t *testing.T) {
+	delay := game.Config.RespawnDelay
+	game.Config.RespawnDelay = 300 * time.Millisecond
+	t.Cleanup(func() { game.Config.RespawnDelay = delay })
+	g, addr := startGateway(t)
+	c, e := dialTest(addr, client.DefaultOptions("p"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer c.Close()
+	c.waitSetup(t)
+
+	s, _, p := killSession(g)
+
+	c.Respawn()
+	c.Respawn()
+	select {
+	case <-c.setup:
+		t.Fatal("respawned before the delay")
+	case <-time.After(100 * time.Millisecond):
+	}
+	c.waitSetup(t)
+	select {
+	case <-c.setup:
+		t.Fatal("respawned twice")
+	case <-time.After(400 * time.Millisecond):
+	}
+	if _, player := s.current(); player == nil || player == p {
+		t.Fatal("no new player")
+	}
+
+	c.Respawn()
+	c.waitSetup(t)
+}
+
+//a dead session released from its stopped battle is still told about the shutdown
+func TestShutdownReachesReleasedSessions(t *testing.T) {
+	idle := game.Config.BattleIdleTimeout
+	game.Config.BattleIdleTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { game.Config.BattleIdleTimeout = idle })
+	g, addr := startGateway(t)
+	c, e := dialTest(addr, client.DefaultOptions("p"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer c.Close()
+	c.waitSetup(t)
+	_, b, _ := killSession(g)
+	if !waitFor(5*time.Second, func() bool { return g.findBattle(b.Id, "") == nil }) {
+		t.Fatal("idle battle not stopped")
+	}
+	g.shutdownSessions()
+	if e := c.waitError(t); e.Code != protocol.ErrorShuttingDown {
+		t.Fatal("error code", e.Code)
+	}
+}
+
+func TestRoomsPerIPLimited(t *testing.T) {
+	setConfig(t, &game.Config.MaxRoomsPerIP, 2)
+	_, addr := startGateway(t)
+	for i := 0; i < 3; i++ {
+		options := client.DefaultOptions("p")
+		options.Private = true
+		c, e := dialTest(addr, options)
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer c.Close()
+		if i < 2 {
+			c.waitSetup(t)
+		} else if e := c.waitError(t); e.Code != protocol.ErrorRateLimited {
+			t.Fatal("error code", e.Code)
+		}
+	}
+}
+
+//wrong private codes are refused before they are looked up once an ip got too many wrong
+func TestWrongCodesLimited(t *testing.T) {
+	setConfig(t, &game.Config.MaxFailedCodeLookups, 2)
+	_, addr := startGateway(t)
+	for i, want := range []protocol.ErrorCode{protocol.ErrorRoomNotFound, protocol.ErrorRoomNotFound, protocol.ErrorRateLimited} {
+		options := client.DefaultOptions("p")
+		options.Code = "0000000000"
+		c, e := dialTest(addr, options)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if e := c.waitError(t); e.Code != want {
+			t.Fatal("lookup", i, "error code", e.Code)
+		}
+		c.Close()
+	}
+}
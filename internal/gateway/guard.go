@@ -0,0 +1,303 @@
+package gateway
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	"go-agar/internal/game"
+	"go-agar/pkg/protocol"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	errBanned             = errors.New("banned")
+	errTooManyConnections = errors.New("too many connections")
+	errTooManyLookups     = errors.New("too many wrong room codes")
+	errTooManyRooms       = errors.New("too many rooms")
+)
+
+//guardConn is a game connection counted against its ip
+type guardConn struct {
+	ip    string
+	close func(e *protocol.Error)
+}
+
+//rateLimiter counts events in windows of period, it is used by a single reader or under a lock
+type rateLimiter struct {
+	limit  int
+	period time.Duration
+	count  int
+	window time.Time
+}
+
+func newRateLimiter(limit int, period time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, period: period}
+}
+
+func (l *rateLimiter) allow() bool {
+	if l.limit <= 0 {
+		return true
+	}
+	if now := time.Now(); now.Sub(l.window) >= l.period {
+		l.window = now
+		l.count = 0
+	}
+	l.count++
+	return l.count <= l.limit
+}
+
+//exhausted tells whether the limit is reached in the current window, without counting
+func (l *rateLimiter) exhausted() bool {
+	return l.limit > 0 && time.Since(l.window) < l.period && l.count >= l.limit
+}
+
+//guard limits the game connections and the wrong room codes per ip and keeps the bans
+type guard struct {
+	conns   map[string][]*guardConn
+	bans    map[string]time.Time
+	lookups map[string]*rateLimiter
+	locker  *sync.Mutex
+}
+
+func newGuard() *guard {
+	return &guard{
+		conns:   make(map[string][]*guardConn),
+		bans:    make(map[string]time.Time),
+		lookups: make(map[string]*rateLimiter),
+		locker:  &sync.Mutex{},
+	}
+}
+
+//lookupFailed counts a room code of the ip that matched no room
+func (g *guard) lookupFailed(ip string) {
+	if game.Config.MaxFailedCodeLookups <= 0 {
+		return
+	}
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	l, ok := g.lookups[ip]
+	if !ok {
+		//ips seen once are dropped when the next one comes in
+		for other, l := range g.lookups {
+			if time.Since(l.window) >= l.period {
+				delete(g.lookups, other)
+			}
+		}
+		l = newRateLimiter(game.Config.MaxFailedCodeLookups, time.Minute)
+		g.lookups[ip] = l
+	}
+	l.allow()
+}
+
+//lookupAllowed tells whether the ip may try another room code
+func (g *guard) lookupAllowed(ip string) bool {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	l, ok := g.lookups[ip]
+	return !ok || !l.exhausted()
+}
+
+func (g *guard) acquire(ip string) (*guardConn, error) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	if g.isBanned(ip) {
+		return nil, errBanned
+	}
+	if game.Config.MaxConnectionsPerIP > 0 && len(g.conns[ip]) >= game.Config.MaxConnectionsPerIP {
+		return nil, errTooManyConnections
+	}
+	c := &guardConn{ip: ip}
+	g.conns[ip] = append(g.conns[ip], c)
+	return c, nil
+}
+
+//attach sets how the connection is closed, telling the client why, it is closed at once if the ip got banned meanwhile
+func (g *guard) attach(c *guardConn, close func(e *protocol.Error)) bool {
+	g.locker.Lock()
+	banned := g.isBanned(c.ip)
+	c.close = close
+	g.locker.Unlock()
+	if banned {
+		close(clientError(errBanned))
+	}
+	return !banned
+}
+
+func (g *guard) release(c *guardConn) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	conns := g.conns[c.ip]
+	for i, c2 := range conns {
+		if c2 == c {
+			conns = append(conns[:i], conns[i+1:]...)
+			break
+		}
+	}
+	if len(conns) == 0 {
+		delete(g.conns, c.ip)
+	} else {
+		g.conns[c.ip] = conns
+	}
+}
+
+//ban rejects the ip until the ban expires and closes its open connections
+func (g *guard) ban(ip string, d time.Duration) time.Time {
+	g.locker.Lock()
+	until := time.Now().Add(d)
+	g.bans[ip] = until
+	var closers []func(e *protocol.Error)
+	for _, c := range g.conns[ip] {
+		if c.close != nil {
+			closers = append(closers, c.close)
+		}
+	}
+	g.locker.Unlock()
+	for _, close := range closers {
+		close(clientError(errBanned))
+	}
+	return until
+}
+
+//closeAll closes every connection in parallel and returns when all are closed or the timeout is reached
+func (g *guard) closeAll(e *protocol.Error, timeout time.Duration) {
+	g.locker.Lock()
+	var closers []func(e *protocol.Error)
+	for _, conns := range g.conns {
+		for _, c := range conns {
+			if c.close != nil {
+				closers = append(closers, c.close)
+			}
+		}
+	}
+	g.locker.Unlock()
+	closeAll(closers, e, timeout)
+}
+
+func (g *guard) unban(ip string) bool {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	_, ok := g.bans[ip]
+	delete(g.bans, ip)
+	return ok
+}
+
+//isBanned must be called with the lock held, expired bans are dropped on the way
+func (g *guard) isBanned(ip string) bool {
+	until, ok := g.bans[ip]
+	if !ok {
+		return false
+	}
+	if time.Now().After(until) {
+		delete(g.bans, ip)
+		return false
+	}
+	return true
+}
+
+func (g *guard) listBans() []gin.H {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	bans := make([]gin.H, 0, len(g.bans))
+	for ip := range g.bans {
+		if g.isBanned(ip) {
+			bans = append(bans, gin.H{
+				"ip":    ip,
+				"until": g.bans[ip],
+			})
+		}
+	}
+	return bans
+}
+
+//checkOrigin accepts browsers on the server host or an allowed origin, and clients sending no origin
+func (g *Gateway) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, e := url.Parse(origin)
+	if e != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range game.Config.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) || strings.EqualFold(allowed, u.Host) {
+			return true
+		}
+	}
+	g.log.WithField("origin", origin).Warn("origin rejected")
+	return false
+}
+
+//admitSession counts the connection against the ip of the request, or answers why it is refused
+func (g *Gateway) admitSession(context *gin.Context) *guardConn {
+	ip := context.ClientIP()
+	c, e := g.guard.acquire(ip)
+	if e == nil && context.Query("code") != "" && !g.guard.lookupAllowed(ip) {
+		g.guard.release(c)
+		e = errTooManyLookups
+	}
+	if e == nil {
+		return c
+	}
+	g.requestLog(context).WithError(e).Warn("session refused")
+	g.refuse(context, e)
+	return nil
+}
+
+//refuse upgrades the request only to tell the client why it is refused, browsers can not read the http status
+func (g *Gateway) refuse(context *gin.Context, e error) {
+	conn, err := g.wsCreator.Upgrade(context.Writer, context.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	if conn.SetWriteDeadline(time.Now().Add(game.Config.WriteTimeout)) != nil {
+		return
+	}
+	conn.WriteMessage(websocket.TextMessage, []byte(protocol.Encode(protocol.ActionError, clientError(e).Encode())))
+}
+
+func (g *Gateway) listBans(c *gin.Context) {
+	c.JSON(http.StatusOK, g.guard.listBans())
+}
+
+func (g *Gateway) addBan(c *gin.Context) {
+	ip := c.Query("ip")
+	if net.ParseIP(ip) == nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	d := game.Config.BanDuration
+	if duration := c.Query("duration"); duration != "" {
+		var e error
+		if d, e = time.ParseDuration(duration); e != nil || d <= 0 {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+	}
+	until := g.guard.ban(ip, d)
+	g.log.WithFields(logrus.Fields{"ip": ip, "until": until}).Warn("ip banned")
+	c.JSON(http.StatusOK, gin.H{
+		"ip":    ip,
+		"until": until,
+	})
+}
+
+func (g *Gateway) removeBan(c *gin.Context) {
+	ip := c.Query("ip")
+	if !g.guard.unban(ip) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	g.log.WithField("ip", ip).Info("ip unbanned")
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"errors"
+	"go-agar/internal/game"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+)
+
+//combining marks allowed on one letter, more only serve to smear text over its neighbours
+const maxCombiningMarks = 2
+
+var errInvalidName = errors.New("name is not allowed")
+
+//sanitizeName folds compatibility forms (fullwidth letters and the like) first so none slip past the filter,
+//then drops what would break the protocol or the page and invisible runes, collapses spaces and cuts the name to max runes
+func sanitizeName(name string, max int) string {
+	var b strings.Builder
+	count, marks, space := 0, 0, false
+	for _, r := range norm.NFKC.String(name) {
+		switch {
+		case r == '|' || r == ',' || r == '<' || r == '>':
+			continue
+		case unicode.IsSpace(r):
+			space = count > 0
+			continue
+		case unicode.In(r, unicode.Mn, unicode.Me):
+			if count == 0 || marks >= maxCombiningMarks {
+				continue
+			}
+			marks++
+		case !unicode.IsPrint(r) || unicode.Is(unicode.Cf, r):
+			continue
+		default:
+			marks = 0
+		}
+		if space {
+			if count+2 > max {
+				break
+			}
+			b.WriteByte(' ')
+			count++
+			space = false
+		}
+		if count >= max {
+			break
+		}
+		b.WriteRune(r)
+		count++
+	}
+	return b.String()
+}
+
+//normalizePlayerName cleans the name and refuses reserved or offensive ones, empty stays empty for an anonymous name
+func normalizePlayerName(name string) (string, error) {
+	name = sanitizeName(name, game.Config.PlayerNameMaxLength)
+	key := game.NameKey(name)
+	for _, reserved := range game.Config.ReservedNames {
+		if key == game.NameKey(reserved) {
+			return "", errInvalidName
+		}
+	}
+	for _, word := range game.Config.BannedNameWords {
+		if word != "" && strings.Contains(key, game.NameKey(word)) {
+			return "", errInvalidName
+		}
+	}
+	return name, nil
+}
+
+func normalizeRoomName(name string) string {
+	return sanitizeName(name, game.Config.RoomNameMaxLength)
+}
@@ -0,0 +1,16 @@
+package gateway
+
+import "testing"
+
+func TestNormalizePlayerName(t *testing.T) {
+	for _, name := range []string{"admin", "ａｄｍｉｎ", "аdmin", " Ａdmin "} {
+		if _, e := normalizePlayerName(name); e != errInvalidName {
+			t.Errorf("%q accepted", name)
+		}
+	}
+	for name, want := range map[string]string{"ｂｏｂ": "bob", "a｜b": "ab", "  al   ice ": "al ice"} {
+		if got, e := normalizePlayerName(name); e != nil || got != want {
+			t.Errorf("%q normalized to %q, %v", name, got, e)
+		}
+	}
+}
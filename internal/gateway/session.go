@@ -1,171 +1,537 @@
 package gateway
 
 import (
-	"fmt"
+	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
 	"go-agar/internal/game"
+	"go-agar/internal/util"
+	"go-agar/pkg/protocol"
+	"io"
+	"net"
+	"sort"
 	"strconv"
-	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+//pings waiting for an echo, so a latency above PingInterval is still measured
+const maxPendingPings = 16
+
 type Session struct {
-	name      string
-	conn      *websocket.Conn
-	player    *game.Player
-	battle    *game.Battle
-	broadcast chan *Chat
-	locker    *sync.Mutex
+	id               string
+	name             string
+	color            string
+	skin             string
+	remote           string
+	conn             Conn
+	player           *game.Player
+	battle           *game.Battle
+	dead             bool
+	diedAt           time.Time
+	respawnQueued    bool
+	deaths           int
+	stats            game.Stats
+	achievements     map[string]bool
+	playerLocker     *sync.Mutex
+	broadcast        chan *protocol.Chat
+	outbound         chan string
+	status           chan string
+	staleStatus      int32
+	done             chan byte
+	written          chan byte
+	closeOnce        *sync.Once
+	pingsSent        []string
+	latency          time.Duration
+	highLatencySince time.Time
+	latencyLocker    *sync.Mutex
+	openTime         time.Time
+	log              *logrus.Entry
 }
 
-func NewSession(name string, conn *websocket.Conn) *Session {
-	broadcast := make(chan *Chat, 10)
-	return &Session{
-		name:      name,
-		conn:      conn,
-		broadcast: broadcast,
-		locker:    &sync.Mutex{},
+func NewSession(name string, remote string, conn Conn, log *logrus.Entry) *Session {
+	broadcast := make(chan *protocol.Chat, 10)
+	id := util.GenId()
+	s := &Session{
+		id:            id,
+		name:          name,
+		remote:        remote,
+		conn:          conn,
+		broadcast:     broadcast,
+		outbound:      make(chan string, game.Config.SessionQueueSize),
+		status:        make(chan string, 1),
+		done:          make(chan byte),
+		written:       make(chan byte),
+		closeOnce:     &sync.Once{},
+		latencyLocker: &sync.Mutex{},
+		playerLocker:  &sync.Mutex{},
+		achievements:  make(map[string]bool),
+		openTime:      time.Now(),
+		log:           log.WithFields(logrus.Fields{"session": id, "name": name}),
 	}
+	go s.writeLoop()
+	return s
 }
 
 func (s *Session) close() {
-	if e := s.conn.Close(); e == nil {
-		if s.battle != nil && s.player != nil {
-			s.battle.RemovePlayer(s.player)
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.log.WithField("duration", time.Since(s.openTime).Round(time.Millisecond).String()).Info("session closed")
+		if b, p := s.current(); b != nil && p != nil {
+			b.RemovePlayer(p)
 			select {
-			case s.broadcast <- NewSystemChat("player [ " + s.player.Name + " ] exit"):
+			case s.broadcast <- protocol.NewSystemChat("player [ " + p.Name + " ] exit"):
 			default:
 			}
 		}
+	})
+}
+
+//an empty payload is a ping from the client, otherwise it is the echo of pingClient
+func (s *Session) ping(payload string) {
+	if payload == "" {
+		s.send(protocol.ActionPing, "")
+		return
+	}
+	if !s.pingEchoed(payload) {
+		return
 	}
+	nano, e := strconv.ParseInt(payload, 10, 64)
+	if e != nil {
+		return
+	}
+	s.updateLatency(time.Since(time.Unix(0, nano)))
 }
 
-func (s *Session) ping() {
-	s.send(ActionPing, "")
+func (s *Session) pingClient() {
+	payload := strconv.FormatInt(time.Now().UnixNano(), 10)
+	s.latencyLocker.Lock()
+	s.pingsSent = append(s.pingsSent, payload)
+	if len(s.pingsSent) > maxPendingPings {
+		s.pingsSent = s.pingsSent[1:]
+	}
+	s.latencyLocker.Unlock()
+	s.send(protocol.ActionPing, payload)
 }
 
-func (s *Session) notify(msg string) {
-	s.send(ActionChat, msg)
+//pingEchoed takes the payload out of the pings waiting for an echo, the ones sent before it are not waited for anymore
+func (s *Session) pingEchoed(payload string) bool {
+	s.latencyLocker.Lock()
+	defer s.latencyLocker.Unlock()
+	for i, sent := range s.pingsSent {
+		if sent == payload {
+			s.pingsSent = s.pingsSent[i+1:]
+			return true
+		}
+	}
+	return false
 }
 
-func (s *Session) join(b *game.Battle) bool {
-	if s.player == nil {
-		s.player = game.NewPlayer(s.name)
+func (s *Session) updateLatency(rtt time.Duration) {
+	if rtt < 0 {
+		return
 	}
-	if !b.AddPlayer(s.player) {
-		return false
+	s.latencyLocker.Lock()
+	if s.latency == 0 {
+		s.latency = rtt
+	} else {
+		s.latency += time.Duration(float64(rtt-s.latency) * game.Config.LatencySmoothing)
+	}
+	kick := false
+	if game.Config.MaxLatency > 0 && s.latency > game.Config.MaxLatency {
+		if s.highLatencySince.IsZero() {
+			s.highLatencySince = time.Now()
+		} else if time.Since(s.highLatencySince) > game.Config.MaxLatencyDuration {
+			kick = true
+		}
+	} else {
+		s.highLatencySince = time.Time{}
+	}
+	s.latencyLocker.Unlock()
+	if kick {
+		s.log.WithField("latency", s.Latency().String()).Warn("kicked for high latency")
+		s.reject(protocol.NewError(protocol.ErrorKicked, "kicked for high latency"))
+	}
+}
+
+func (s *Session) Latency() time.Duration {
+	s.latencyLocker.Lock()
+	defer s.latencyLocker.Unlock()
+	return s.latency
+}
+
+func (s *Session) notify(c *protocol.Chat) {
+	s.send(protocol.ActionChat, c.Encode())
+}
+
+//current returns the battle and the player of the session, both change when the player respawns
+func (s *Session) current() (*game.Battle, *game.Player) {
+	s.playerLocker.Lock()
+	defer s.playerLocker.Unlock()
+	return s.battle, s.player
+}
+
+func (s *Session) join(b *game.Battle) error {
+	_, p := s.current()
+	if p == nil {
+		p = game.NewPlayer(s.name, s.color, s.skin)
+	}
+	if e := b.AddPlayer(p); e != nil {
+		return e
+	}
+	s.playerLocker.Lock()
+	select {
+	case <-s.done:
+		//closed while joining, close already removed the previous player if any
+		s.playerLocker.Unlock()
+		b.RemovePlayer(p)
+		return errSessionClosed
+	default:
 	}
 	s.battle = b
-	s.send(ActionGameSetup, fmt.Sprintf("%.0f|%.0f|%.0f|%.0f|%s", game.Config.GameWidth, game.Config.GameHeight, game.Config.ScreenWidth, game.Config.ScreenHeight, game.Config.VirusColor))
+	s.player = p
+	s.dead = false
+	s.playerLocker.Unlock()
+	s.log.WithFields(logrus.Fields{"battle": b.Id, "room": b.Name}).Info("joined battle")
+	s.sendSetup(b)
 	select {
-	case s.broadcast <- NewSystemChat("player [ " + s.player.Name + " ] join"):
+	case s.broadcast <- protocol.NewSystemChat("player [ " + p.Name + " ] join"):
 	default:
 	}
-	return true
+	return nil
 }
 
+func (s *Session) sendSetup(b *game.Battle) {
+	setup := &protocol.GameSetup{
+		Width:        game.Config.GameWidth,
+		Height:       game.Config.GameHeight,
+		ScreenWidth:  game.Config.ScreenWidth,
+		ScreenHeight: game.Config.ScreenHeight,
+		VirusColor:   game.Config.VirusColor,
+		RoomName:     b.Name,
+		RoomCode:     b.Code,
+		RespawnDelay: game.Config.RespawnDelay.Milliseconds(),
+		TickRate:     game.Config.TickRate,
+	}
+	s.send(protocol.ActionGameSetup, setup.Encode())
+}
+
+//reject tells the client why it can not play, the message is written out before the connection closes
+func (s *Session) reject(e error) {
+	pe := clientError(e)
+	s.log.WithError(e).WithField("code", pe.Code).Info("session rejected")
+	s.send(protocol.ActionError, pe.Encode())
+	s.close()
+}
+
+//shut rejects the session and returns once the reason is written
+func (s *Session) shut(e *protocol.Error) {
+	s.reject(e)
+	s.wait()
+}
+
+//pushPlayerStatus runs on the battle mount, after the death is sent the session stays open until the player respawns or leaves
 func (s *Session) pushPlayerStatus() {
-	if s.player != nil {
-		s.send(ActionPlayerStatus, s.fmtPlayerStatus())
-		if s.player.IsDied() {
-			s.close()
+	s.playerLocker.Lock()
+	p, dead := s.player, s.dead
+	s.playerLocker.Unlock()
+	if p == nil || dead {
+		return
+	}
+	view := p.View()
+	for _, a := range view.Achievements {
+		s.unlock(a)
+	}
+	if view.Death == nil {
+		s.sendStatus(s.playerStatus(view, p.Id).Encode())
+		return
+	}
+	s.playerLocker.Lock()
+	if s.player == p {
+		s.dead = true
+		s.diedAt = time.Now()
+		s.deaths++
+		s.stats.Add(view.Death.Stats)
+	}
+	s.playerLocker.Unlock()
+	s.log.WithFields(logrus.Fields{
+		"killer":   view.Death.Killer,
+		"peakMass": view.Death.Stats.PeakMass,
+		"alive":    view.Death.Stats.SurvivalTime.Round(time.Second).String(),
+	}).Info("player died")
+	s.send(protocol.ActionDeath, deathMessage(view.Death).Encode())
+}
+
+//unlock tells the player about an achievement, once per session however often it respawns
+func (s *Session) unlock(a *game.Achievement) {
+	s.playerLocker.Lock()
+	unlocked := s.achievements[a.Id]
+	s.achievements[a.Id] = true
+	s.playerLocker.Unlock()
+	if unlocked {
+		return
+	}
+	s.log.WithField("achievement", a.Id).Debug("achievement unlocked")
+	s.send(protocol.ActionAchievement, (&protocol.Achievement{Id: a.Id, Name: a.Name, Description: a.Description}).Encode())
+}
+
+//summary is what the admin api shows about the play of the session
+func (s *Session) summary() gin.H {
+	s.playerLocker.Lock()
+	defer s.playerLocker.Unlock()
+	achievements := make([]string, 0, len(s.achievements))
+	for id := range s.achievements {
+		achievements = append(achievements, id)
+	}
+	sort.Strings(achievements)
+	return gin.H{
+		"deaths":       s.deaths,
+		"stats":        s.stats,
+		"achievements": achievements,
+	}
+}
+
+//playing tells whether the session has a live player in b
+func (s *Session) playing(b *game.Battle) bool {
+	s.playerLocker.Lock()
+	defer s.playerLocker.Unlock()
+	return s.battle == b && s.player != nil && !s.dead
+}
+
+//respawnable clears the dead player once RespawnDelay has passed, the battle it died in is returned for the new one.
+//Before that the time left is returned to the first early request only, so a single retry is queued
+func (s *Session) respawnable() (*game.Battle, time.Duration) {
+	s.playerLocker.Lock()
+	defer s.playerLocker.Unlock()
+	if !s.dead {
+		return nil, 0
+	}
+	if wait := game.Config.RespawnDelay - time.Since(s.diedAt); wait > 0 {
+		if s.respawnQueued {
+			return nil, 0
 		}
+		s.respawnQueued = true
+		return nil, wait
 	}
+	s.player = nil
+	s.dead = false
+	s.respawnQueued = false
+	return s.battle, 0
 }
 
 func (s *Session) pushLeaderBoard() {
-	s.send(ActionLeaderBoard, s.fmtLeaderBoard())
+	if b, _ := s.current(); b != nil {
+		s.send(protocol.ActionLeaderBoard, leaderBoardMessage(b.LeaderBoard()).Encode())
+	}
 }
 
 func (s *Session) say(msg string) {
-	if s.battle != nil && s.player != nil {
+	if b, p := s.current(); b != nil && p != nil {
 		select {
-		case s.broadcast <- NewPlayerChat(s.player.Name + " : " + msg):
+		case s.broadcast <- protocol.NewPlayerChat(p.Name + " : " + msg):
 		default:
 		}
 	}
 }
 
-func (s *Session) move(c *websocket.Conn, payload string) {
-	if p := s.player; p != nil {
-		split := strings.Split(payload, ",")
-		x, e := strconv.Atoi(split[0])
-		if e != nil {
-			return
-		}
-		y, e := strconv.Atoi(split[1])
+func (s *Session) move(payload string) {
+	if b, p := s.current(); b != nil && p != nil {
+		m, e := protocol.ParseMove(payload)
 		if e != nil {
 			return
 		}
-		p.MoveTo(float64(x), float64(y))
+		b.Move(p, float64(m.X), float64(m.Y), m.Seq)
 	}
 }
 
 func (s *Session) fire() {
-	if s.player != nil {
-		s.player.FireFood()
+	if b, p := s.current(); b != nil && p != nil {
+		b.Fire(p)
 	}
 }
 
 func (s *Session) split() {
-	if s.player != nil {
-		s.player.SplitAll()
+	if b, p := s.current(); b != nil && p != nil {
+		b.Split(p)
 	}
 }
 
 func (s *Session) send(msgType string, data string) {
-	s.locker.Lock()
-	defer s.locker.Unlock()
-	retry := 0
-	for retry < 3 {
-		e := s.conn.WriteMessage(websocket.TextMessage, []byte(msgType+"|"+data))
-		if e == nil {
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+	select {
+	case s.outbound <- protocol.Encode(msgType, data):
+	default:
+		//the client can not keep up with the queue
+		s.log.WithField("queue", cap(s.outbound)).Warn("outbound queue full")
+		s.close()
+	}
+}
+
+//only the latest status matters, a frame not yet written is replaced by the new one
+func (s *Session) sendStatus(data string) {
+	msg := protocol.Encode(protocol.ActionPlayerStatus, data)
+	for {
+		select {
+		case <-s.done:
+			return
+		case s.status <- msg:
 			return
+		default:
+		}
+		select {
+		case <-s.status:
+			if atomic.AddInt32(&s.staleStatus, 1) > int32(game.Config.MaxStaleStatus) {
+				s.log.WithField("stale", game.Config.MaxStaleStatus).Warn("status frames not consumed")
+				s.reject(protocol.NewError(protocol.ErrorKicked, "client is too slow"))
+				return
+			}
+		default:
+		}
+	}
+}
+
+//wait returns once the writer has flushed what was queued and closed the connection
+func (s *Session) wait() {
+	<-s.written
+}
+
+func (s *Session) writeLoop() {
+	defer close(s.written)
+	defer s.conn.Close()
+	for {
+		select {
+		case <-s.done:
+			s.flush()
+			return
+		case msg := <-s.outbound:
+			if !s.write(msg) {
+				s.close()
+				return
+			}
+		case msg := <-s.status:
+			if !s.write(msg) {
+				s.close()
+				return
+			}
+			atomic.StoreInt32(&s.staleStatus, 0)
 		}
-		retry++
 	}
-	s.close()
 }
 
-func (s *Session) fmtLeaderBoard() string {
-	b := s.battle
-	if b == nil {
-		return ""
+//write out what is still queued, such as the reason of a kick
+func (s *Session) flush() {
+	for {
+		select {
+		case msg := <-s.outbound:
+			if !s.write(msg) {
+				return
+			}
+		default:
+			return
+		}
 	}
-	if len(b.LeaderBoard) == 0 {
-		return ""
+}
+
+func (s *Session) write(msg string) bool {
+	e := s.conn.SetWriteDeadline(time.Now().Add(game.Config.WriteTimeout))
+	if e == nil {
+		e = s.conn.WriteMessage(websocket.TextMessage, []byte(msg))
 	}
-	var sb strings.Builder
-	for _, name := range b.LeaderBoard {
-		sb.WriteString(name)
-		sb.WriteString(",")
+	if e != nil {
+		s.log.WithError(e).Debug("write error")
+		return false
 	}
-	res := sb.String()
-	return res[:len(res)-1]
+	return true
 }
 
-func (s *Session) fmtPlayerStatus() string {
-	p := s.player
-	if p == nil {
-		return ""
+//logReadError tells a client leaving from a broken connection
+func (s *Session) logReadError(e error) {
+	select {
+	case <-s.done:
+		//closed by the server, the reason is already logged
+		s.log.WithError(e).Debug("read stopped")
+		return
+	default:
 	}
-	var result strings.Builder
-	fmt.Fprintf(&result, "%s,%.2f,%.2f,%.2f", p.Name, p.X, p.Y, p.MassTotal)
-	fmt.Fprintf(&result, "|%d", len(p.VisibleCells))
-	for _, v := range p.VisibleCells {
-		fmt.Fprintf(&result, "|%s,%s,%s,%.2f,%.2f,%.2f", v.Name, v.Color, v.TextColor, v.X, v.Y, v.Radius)
+	if e == io.EOF || websocket.IsCloseError(e, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		s.log.Debug("connection closed by client")
+		return
 	}
-	fmt.Fprintf(&result, "|%d", len(p.VisibleFoods))
-	for _, v := range p.VisibleFoods {
-		fmt.Fprintf(&result, "|%.2f,%.2f,%.2f,%s", v.X, v.Y, v.Radius, v.Color)
+	if websocket.IsCloseError(e, websocket.CloseAbnormalClosure) {
+		s.log.Info("connection lost")
+		return
 	}
-	fmt.Fprintf(&result, "|%d", len(p.VisibleMassFoods))
-	for _, v := range p.VisibleMassFoods {
-		fmt.Fprintf(&result, "|%.2f,%.2f,%.2f,%s", v.X, v.Y, v.Radius, v.Color)
+	if ne, ok := e.(net.Error); ok && ne.Timeout() {
+		s.log.Info("heartbeat timeout")
+		return
 	}
-	fmt.Fprintf(&result, "|%d", len(p.VisibleViruses))
-	for _, v := range p.VisibleViruses {
-		fmt.Fprintf(&result, "|%.2f,%.2f,%.2f", v.X, v.Y, v.Radius)
+	s.log.WithError(e).Warn("read error")
+}
+
+func leaderBoardMessage(l *game.LeaderBoard) *protocol.LeaderBoard {
+	return &protocol.LeaderBoard{
+		Tick:  l.Tick,
+		Time:  toMillis(l.Time),
+		Names: l.Names,
 	}
-	return result.String()
+}
+
+func deathMessage(d *game.Death) *protocol.Death {
+	return &protocol.Death{
+		Killer:     d.Killer,
+		PeakMass:   d.Stats.PeakMass,
+		TimeAlive:  d.Stats.SurvivalTime.Milliseconds(),
+		FoodsEaten: d.Stats.FoodsEaten,
+		CellsEaten: d.Stats.CellsEaten,
+	}
+}
+
+func (s *Session) playerStatus(p *game.PlayerView, playerId string) *protocol.PlayerStatus {
+	status := &protocol.PlayerStatus{
+		Name:      p.Name,
+		X:         p.X,
+		Y:         p.Y,
+		MassTotal: p.MassTotal,
+		LastInput: p.LastInput,
+		Tick:      p.Tick,
+		Time:      toMillis(p.Time),
+		Cells:     make([]protocol.Cell, len(p.Cells)),
+		Foods:     make([]protocol.Food, len(p.Foods)),
+		MassFoods: make([]protocol.MassFood, len(p.MassFoods)),
+		Viruses:   make([]protocol.Virus, len(p.Viruses)),
+	}
+	for i, v := range p.Cells {
+		status.Cells[i] = protocol.Cell{
+			Name:      v.Name,
+			Color:     v.Color,
+			TextColor: v.TextColor,
+			X:         v.X,
+			Y:         v.Y,
+			Radius:    v.Radius,
+			Id:        v.Id,
+			VX:        v.VX,
+			VY:        v.VY,
+			Mine:      v.PlayerId == playerId,
+			Skin:      v.Skin,
+		}
+	}
+	for i, v := range p.Foods {
+		status.Foods[i] = protocol.Food{X: v.X, Y: v.Y, Radius: v.Radius, Color: v.Color}
+	}
+	for i, v := range p.MassFoods {
+		status.MassFoods[i] = protocol.MassFood{X: v.X, Y: v.Y, Radius: v.Radius, Color: v.Color}
+	}
+	for i, v := range p.Viruses {
+		status.Viruses[i] = protocol.Virus{X: v.X, Y: v.Y, Radius: v.Radius}
+	}
+	return status
+}
+
+func toMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
 }
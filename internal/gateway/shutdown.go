@@ -0,0 +1,70 @@
+package gateway
+
+import (
+	"context"
+	"go-agar/internal/game"
+	"go-agar/pkg/protocol"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+//onSignal calls shutdown once the process is asked to stop
+func onSignal(shutdown func()) {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-quit
+		signal.Stop(quit)
+		shutdown()
+	}()
+}
+
+//closeAll runs the closers in parallel and returns when all are done or the timeout is reached
+func closeAll(closers []func(e *protocol.Error), e *protocol.Error, timeout time.Duration) {
+	wg := &sync.WaitGroup{}
+	for _, close := range closers {
+		wg.Add(1)
+		go func(close func(e *protocol.Error)) {
+			defer wg.Done()
+			close(e)
+		}(close)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+//shutdownSessions tells the sessions of this process that the server goes away
+func (g *Gateway) shutdownSessions() {
+	g.battleLocker.Lock()
+	closers := make([]func(e *protocol.Error), 0, len(g.sessionBattles))
+	for s := range g.sessionBattles {
+		closers = append(closers, s.shut)
+	}
+	g.battleLocker.Unlock()
+	g.log.WithField("sessions", len(closers)).Info("shutting down")
+	closeAll(closers, protocol.NewError(protocol.ErrorShuttingDown, ""), game.Config.ShutdownTimeout)
+}
+
+//shutdownServer tells every client that the server goes away, then stops accepting requests on all servers
+func (g *Gateway) shutdownServer(servers ...*http.Server) {
+	g.log.Info("shutting down")
+	g.guard.closeAll(protocol.NewError(protocol.ErrorShuttingDown, ""), game.Config.ShutdownTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), game.Config.ShutdownTimeout)
+	defer cancel()
+	for _, server := range servers {
+		if e := server.Shutdown(ctx); e != nil {
+			g.log.WithError(e).WithField("addr", server.Addr).Warn("shutdown incomplete")
+		}
+	}
+}
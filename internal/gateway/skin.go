@@ -0,0 +1,116 @@
+package gateway
+
+import (
+	"github.com/gin-gonic/gin"
+	"go-agar/internal/asset"
+	"go-agar/internal/game"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//a skin is referred to by its file name, which also goes into the status frame.
+//No svg, it is served from the origin of the game and could run script
+var skinName = regexp.MustCompile(`^[A-Za-z0-9_-]+\.(png|jpg|jpeg|gif|webp)$`)
+
+//skinRegistry knows the skins in SkinDir, rescanning it so admins can drop in new ones
+type skinRegistry struct {
+	dir      string
+	skins    map[string]bool
+	scanned  time.Time
+	locker   *sync.Mutex
+	handler  http.Handler
+	colorSet map[string]bool
+}
+
+func newSkinRegistry(dir string) *skinRegistry {
+	r := &skinRegistry{
+		dir:      dir,
+		skins:    make(map[string]bool),
+		locker:   &sync.Mutex{},
+		handler:  http.StripPrefix("/skins", asset.NewServer(os.DirFS(dir), false, game.Config.AssetMaxAge)),
+		colorSet: make(map[string]bool),
+	}
+	for _, color := range game.Config.PlayerColors {
+		r.colorSet[strings.ToLower(color)] = true
+	}
+	return r
+}
+
+func (r *skinRegistry) scan() {
+	if time.Since(r.scanned) < game.Config.SkinScanInterval {
+		return
+	}
+	r.scanned = time.Now()
+	skins := make(map[string]bool)
+	infos, e := ioutil.ReadDir(r.dir)
+	if e != nil && !os.IsNotExist(e) {
+		game.Log.WithError(e).WithField("dir", r.dir).Warn("skin scan failed")
+	}
+	for _, info := range infos {
+		if !info.IsDir() && skinName.MatchString(info.Name()) {
+			skins[info.Name()] = true
+		}
+	}
+	r.skins = skins
+}
+
+func (r *skinRegistry) list() []string {
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	r.scan()
+	skins := make([]string, 0, len(r.skins))
+	for skin := range r.skins {
+		skins = append(skins, skin)
+	}
+	sort.Strings(skins)
+	return skins
+}
+
+//skin returns the skin if it is known, otherwise none
+func (r *skinRegistry) skin(name string) string {
+	if name == "" {
+		return ""
+	}
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	r.scan()
+	if !r.skins[name] {
+		return ""
+	}
+	return name
+}
+
+//color returns the color if it is one of PlayerColors, otherwise none so a random one is used
+func (r *skinRegistry) color(color string) string {
+	color = strings.ToLower(color)
+	if !r.colorSet[color] {
+		return ""
+	}
+	return color
+}
+
+//serve only hands out the skins, not whatever else lies in the directory
+func (r *skinRegistry) serve(c *gin.Context) {
+	if r.skin(strings.TrimPrefix(c.Param("file"), "/")) == "" {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	//skins come from admins but are still kept from being run as a page
+	c.Header("Content-Security-Policy", "default-src 'none'")
+	c.Header("X-Content-Type-Options", "nosniff")
+	r.handler.ServeHTTP(c.Writer, c.Request)
+}
+
+func (g *Gateway) listLooks(c *gin.Context) {
+	skins := g.skins.list()
+	c.JSON(http.StatusOK, gin.H{
+		"colors": game.Config.PlayerColors,
+		"skins":  skins,
+	})
+}
@@ -0,0 +1,121 @@
+package gateway
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/sirupsen/logrus"
+	"go-agar/internal/game"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func (g *Gateway) listen(handler http.Handler) error {
+	addr := net.JoinHostPort(game.Config.Host, strconv.Itoa(game.Config.Port))
+	server := &http.Server{
+		Addr:     addr,
+		Handler:  handler,
+		ErrorLog: log.New(g.log.WriterLevel(logrus.DebugLevel), "", 0),
+	}
+	secure := game.Config.TLSCert != "" && game.Config.TLSKey != ""
+	if !secure && game.Config.TLSSelfSigned {
+		cert, e := selfSignedCert(game.Config.Host)
+		if e != nil {
+			return e
+		}
+		server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+		secure = true
+		g.log.Warn("serving a self signed certificate, do not use it in production")
+	}
+	servers := []*http.Server{server}
+	if secure && game.Config.HTTPRedirectPort > 0 {
+		redirect := g.redirectServer()
+		servers = append(servers, redirect)
+		go func() {
+			g.log.WithField("addr", redirect.Addr).Info("http redirect start")
+			if e := ignoreClosed(redirect.ListenAndServe()); e != nil {
+				g.log.WithError(e).Error("http redirect stop")
+			}
+		}()
+	}
+	onSignal(func() {
+		g.shutdownServer(servers...)
+	})
+	if !secure {
+		g.log.WithField("addr", addr).Info("server start")
+		return ignoreClosed(server.ListenAndServe())
+	}
+	g.log.WithField("addr", addr).Info("server start with tls")
+	//empty file names make the server use TLSConfig
+	return ignoreClosed(server.ListenAndServeTLS(game.Config.TLSCert, game.Config.TLSKey))
+}
+
+//ignoreClosed drops the error returned after a graceful shutdown
+func ignoreClosed(e error) error {
+	if e == http.ErrServerClosed {
+		return nil
+	}
+	return e
+}
+
+//redirectServer sends plain http requests to the https port, it is shut down along with the main server
+func (g *Gateway) redirectServer() *http.Server {
+	return &http.Server{
+		Addr: net.JoinHostPort(game.Config.Host, strconv.Itoa(game.Config.HTTPRedirectPort)),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, _, e := net.SplitHostPort(r.Host)
+			if e != nil {
+				host = r.Host
+			}
+			if game.Config.Port != 443 {
+				host = net.JoinHostPort(host, strconv.Itoa(game.Config.Port))
+			}
+			http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+		}),
+		ErrorLog: log.New(g.log.WriterLevel(logrus.DebugLevel), "", 0),
+	}
+}
+
+//selfSignedCert creates a certificate valid for localhost and host for a year
+func selfSignedCert(host string) (tls.Certificate, error) {
+	key, e := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if e != nil {
+		return tls.Certificate{}, e
+	}
+	serial, e := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if e != nil {
+		return tls.Certificate{}, e
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"go-agar"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = append(template.IPAddresses, ip)
+	} else if host != "" {
+		template.DNSNames = append(template.DNSNames, host)
+	}
+	der, e := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if e != nil {
+		return tls.Certificate{}, e
+	}
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}, nil
+}
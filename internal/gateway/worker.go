@@ -0,0 +1,159 @@
+package gateway
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	"go-agar/internal/game"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	handshakeGame   = "game?"
+	handshakeStatus = "status"
+)
+
+//added by the gateway to the query it relays, so worker logs can be matched with its own
+const (
+	queryRequestId = "requestId"
+	queryRemote    = "remote"
+)
+
+var errNoWorkerSecret = errors.New("WorkerSecret must be set for battle workers")
+
+type workerStatus struct {
+	Players int           `json:"players"`
+	Battles int           `json:"battles"`
+	Rooms   []*workerRoom `json:"rooms"`
+}
+
+//workerRoom is a battle as told to the gateway, the join code of a private room is replaced by its key
+type workerRoom struct {
+	game.BattleInfo
+	CodeKey string `json:"codeKey,omitempty"`
+}
+
+//codeKey lets the gateway find a private room by code without the worker telling the code
+func codeKey(code string) string {
+	mac := hmac.New(sha256.New, []byte(game.Config.WorkerSecret))
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+//RunWorker serves battles to a gateway over tcp instead of to browsers over http
+func (g *Gateway) RunWorker(addr string) error {
+	if game.Config.WorkerSecret == "" {
+		return errNoWorkerSecret
+	}
+	listener, e := net.Listen("tcp", addr)
+	if e != nil {
+		return e
+	}
+	stopping := make(chan struct{})
+	onSignal(func() {
+		close(stopping)
+		g.shutdownSessions()
+		listener.Close()
+	})
+	e = g.serveWorker(listener)
+	select {
+	case <-stopping:
+		return nil
+	default:
+		return e
+	}
+}
+
+//serveWorker accepts gateway connections until the listener is closed
+func (g *Gateway) serveWorker(listener net.Listener) error {
+	defer g.Stop()
+	defer listener.Close()
+	if game.Config.WorkerSecret == "" {
+		return errNoWorkerSecret
+	}
+	g.log = game.Log.WithField("component", "worker")
+	g.log.WithField("addr", listener.Addr().String()).Info("worker start")
+	for {
+		conn, e := listener.Accept()
+		if e != nil {
+			return e
+		}
+		go g.acceptWorkerConn(newFrameConn(conn), conn.RemoteAddr().String())
+	}
+}
+
+//acceptWorkerConn expects WorkerSecret in the first frame and the handshake in the second
+func (g *Gateway) acceptWorkerConn(conn *frameConn, remote string) {
+	log := g.log.WithField("gateway", remote)
+	if e := conn.SetReadDeadline(time.Now().Add(game.Config.WorkerDialTimeout)); e != nil {
+		conn.Close()
+		return
+	}
+	_, secret, e := conn.ReadMessage()
+	if e == nil && subtle.ConstantTimeCompare(secret, []byte(game.Config.WorkerSecret)) != 1 {
+		log.Warn("worker authentication failed")
+		conn.Close()
+		return
+	}
+	var bytes []byte
+	if e == nil {
+		_, bytes, e = conn.ReadMessage()
+	}
+	if e != nil {
+		log.WithError(e).Debug("handshake failed")
+		conn.Close()
+		return
+	}
+	handshake := string(bytes)
+	switch {
+	case handshake == handshakeStatus:
+		defer conn.Close()
+		data, e := json.Marshal(g.workerStatus())
+		if e != nil {
+			return
+		}
+		conn.SetWriteDeadline(time.Now().Add(game.Config.WriteTimeout))
+		conn.WriteMessage(websocket.TextMessage, data)
+	case strings.HasPrefix(handshake, handshakeGame):
+		query, e := url.ParseQuery(handshake[len(handshakeGame):])
+		if e != nil {
+			log.WithError(e).Warn("bad session handshake")
+			conn.Close()
+			return
+		}
+		g.serveSession(conn, query.Get(queryRemote), query, log.WithFields(logrus.Fields{
+			"requestId": query.Get(queryRequestId),
+			"remote":    query.Get(queryRemote),
+		}), nil)
+	default:
+		log.Warn("unknown handshake")
+		conn.Close()
+	}
+}
+
+func (g *Gateway) workerStatus() *workerStatus {
+	g.battleLocker.Lock()
+	defer g.battleLocker.Unlock()
+	status := &workerStatus{
+		Players: len(g.sessionBattles),
+		Battles: len(g.battles),
+		Rooms:   make([]*workerRoom, len(g.battles)),
+	}
+	for i, b := range g.battles {
+		room := &workerRoom{BattleInfo: *b.Info()}
+		if room.Code != "" {
+			room.CodeKey = codeKey(room.Code)
+			room.Code = ""
+		}
+		status.Rooms[i] = room
+	}
+	return status
+}
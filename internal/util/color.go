@@ -0,0 +1,149 @@
+package util
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+)
+
+const (
+	TextBlack = "#000000"
+	TextWhite = "#ffffff"
+)
+
+//ParseColor reads "#rrggbb" into channels between 0 and 1
+func ParseColor(hex string) (r, g, b float64, ok bool) {
+	if len(hex) != 7 || hex[0] != '#' {
+		return 0, 0, 0, false
+	}
+	v, e := strconv.ParseUint(hex[1:], 16, 32)
+	if e != nil {
+		return 0, 0, 0, false
+	}
+	return float64(v>>16&0xff) / 255, float64(v>>8&0xff) / 255, float64(v&0xff) / 255, true
+}
+
+func FormatColor(r, g, b float64) string {
+	channel := func(c float64) string {
+		s := strconv.FormatInt(int64(math.Round(math.Max(0, math.Min(1, c))*255)), 16)
+		if len(s) == 1 {
+			s = "0" + s
+		}
+		return s
+	}
+	return "#" + channel(r) + channel(g) + channel(b)
+}
+
+//HSL builds a color from hue in degrees, saturation and lightness between 0 and 1
+func HSL(h, s, l float64) string {
+	h = math.Mod(math.Mod(h, 360)+360, 360) / 360
+	if s == 0 {
+		return FormatColor(l, l, l)
+	}
+	q := l * (1 + s)
+	if l >= 0.5 {
+		q = l + s - l*s
+	}
+	p := 2*l - q
+	return FormatColor(hueToChannel(p, q, h+1.0/3), hueToChannel(p, q, h), hueToChannel(p, q, h-1.0/3))
+}
+
+func hueToChannel(p, q, t float64) float64 {
+	if t < 0 {
+		t++
+	}
+	if t > 1 {
+		t--
+	}
+	switch {
+	case t < 1.0/6:
+		return p + (q-p)*6*t
+	case t < 0.5:
+		return q
+	case t < 2.0/3:
+		return p + (q-p)*(2.0/3-t)*6
+	}
+	return p
+}
+
+//Hue returns the hue of the color in degrees
+func Hue(hex string) float64 {
+	r, g, b, ok := ParseColor(hex)
+	if !ok {
+		return 0
+	}
+	max, min := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	d := max - min
+	if d == 0 {
+		return 0
+	}
+	var h float64
+	switch max {
+	case r:
+		h = math.Mod((g-b)/d, 6)
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	return math.Mod(h*60+360, 360)
+}
+
+//HueDistance is the shortest way round the color wheel between two hues
+func HueDistance(a, b float64) float64 {
+	d := math.Abs(a - b)
+	return math.Min(d, 360-d)
+}
+
+//Luminance is the relative luminance of the color as defined by WCAG
+func Luminance(hex string) float64 {
+	r, g, b, ok := ParseColor(hex)
+	if !ok {
+		return 0
+	}
+	linear := func(c float64) float64 {
+		if c <= 0.03928 {
+			return c / 12.92
+		}
+		return math.Pow((c+0.055)/1.055, 2.4)
+	}
+	return 0.2126*linear(r) + 0.7152*linear(g) + 0.0722*linear(b)
+}
+
+//ContrastRatio between two colors, from 1 for none to 21 for black on white
+func ContrastRatio(a, b string) float64 {
+	la, lb := Luminance(a), Luminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+//TextColor picks black or white, whichever reads better on background
+func TextColor(background string) string {
+	if ContrastRatio(background, TextBlack) >= ContrastRatio(background, TextWhite) {
+		return TextBlack
+	}
+	return TextWhite
+}
+
+//ColorDistance is the euclidean distance of two colors in rgb, from 0 to about 1.73
+func ColorDistance(a, b string) float64 {
+	r1, g1, b1, _ := ParseColor(a)
+	r2, g2, b2, _ := ParseColor(b)
+	return math.Sqrt((r1-r2)*(r1-r2) + (g1-g2)*(g1-g2) + (b1-b2)*(b1-b2))
+}
+
+//Lighten mixes the color with white by amount between 0 and 1
+func Lighten(hex string, amount float64) string {
+	r, g, b, ok := ParseColor(hex)
+	if !ok {
+		return hex
+	}
+	return FormatColor(r+(1-r)*amount, g+(1-g)*amount, b+(1-b)*amount)
+}
+
+//RandomHSL returns a color of random hue with saturation and lightness within the ranges
+func RandomHSL(minS, maxS, minL, maxL float64) string {
+	return HSL(rand.Float64()*360, minS+rand.Float64()*(maxS-minS), minL+rand.Float64()*(maxL-minL))
+}
@@ -5,6 +5,8 @@ import (
 	"math"
 	"math/rand"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,17 +19,6 @@ func MassToRadius(mass float64) float64 {
 	return 4 + math.Sqrt(mass)*6
 }
 
-func Color() string {
-	s:="0123456789abcdef"
-	split :=strings.Split(s,"")
-	var b strings.Builder
-	b.WriteString("#")
-	for i:=0;i<6;i++{
-		b.WriteString(split[rand.Intn(16)])
-	}
-	return b.String()
-}
-
 //对角线线距减双方半径
 func GetDistance(x1, y1, radius1, x2, y2, radius2 float64) float64 {
 	return math.Sqrt(math.Pow(x2-x1, 2)+math.Pow(y2-y1, 2)) - radius1 - radius2
@@ -67,3 +58,33 @@ func GenId() string {
 	id := uuid.NewV4()
 	return strings.ReplaceAll(id.String(), "-", "")
 }
+
+//Parallel calls fn for every index in [0,n) spread over at most workers goroutines,
+//small jobs run on the calling goroutine
+func Parallel(n, workers int, fn func(i int)) {
+	if workers > n/2 {
+		workers = n / 2
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+	next := int64(-1)
+	wg := &sync.WaitGroup{}
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= n {
+					return
+				}
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,343 @@
+//Package client connects to a go-agar server and decodes the game protocol into typed messages
+package client
+
+import (
+	"crypto/tls"
+	"errors"
+	"github.com/gorilla/websocket"
+	"go-agar/pkg/protocol"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var ErrClosed = errors.New("client closed")
+
+type Options struct {
+	Name    string
+	Room    string
+	Code    string
+	Create  string
+	Private bool
+	//Color and Skin are ignored by the server unless they are in its allowed lists, see /looks
+	Color string
+	Skin  string
+	//PingInterval keeps the connection alive and measures the latency, 0 disables it
+	PingInterval time.Duration
+	WriteTimeout time.Duration
+	//Insecure accepts any server certificate, such as the self signed one of a development server
+	Insecure bool
+}
+
+func DefaultOptions(name string) *Options {
+	return &Options{
+		Name:         name,
+		PingInterval: 2 * time.Second,
+		WriteTimeout: 2 * time.Second,
+	}
+}
+
+//Handler is called from the read loop of the client, nil funcs are skipped
+type Handler struct {
+	GameSetup    func(*protocol.GameSetup)
+	Chat         func(*protocol.Chat)
+	PlayerStatus func(*protocol.PlayerStatus)
+	LeaderBoard  func(*protocol.LeaderBoard)
+	Error        func(*protocol.Error)
+	Death        func(*protocol.Death)
+	Kill         func(*protocol.Kill)
+	Achievement  func(*protocol.Achievement)
+	//Message sees every message before it is decoded
+	Message func(action string, size int)
+	//Malformed is told about payloads that can not be decoded
+	Malformed func(action string, e error)
+}
+
+type Client struct {
+	conn        *websocket.Conn
+	options     *Options
+	handler     *Handler
+	writeLocker *sync.Mutex
+	seq         int64
+	pingSent    time.Time
+	latency     time.Duration
+	locker      *sync.Mutex
+	err         error
+	done        chan byte
+	closeOnce   *sync.Once
+}
+
+//Dial opens a session on addr, such as ws://localhost:38888/game
+func Dial(addr string, options *Options, handler *Handler) (*Client, error) {
+	u, e := url.Parse(addr)
+	if e != nil {
+		return nil, e
+	}
+	query := u.Query()
+	query.Set("name", options.Name)
+	query.Set("v", strconv.Itoa(protocol.Version))
+	setQuery(query, "room", options.Room)
+	setQuery(query, "code", options.Code)
+	setQuery(query, "create", options.Create)
+	setQuery(query, "color", options.Color)
+	setQuery(query, "skin", options.Skin)
+	if options.Private {
+		query.Set("private", "1")
+	}
+	u.RawQuery = query.Encode()
+	dialer := *websocket.DefaultDialer
+	if options.Insecure {
+		dialer.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	conn, _, e := dialer.Dial(u.String(), nil)
+	if e != nil {
+		return nil, e
+	}
+	if handler == nil {
+		handler = &Handler{}
+	}
+	c := &Client{
+		conn:        conn,
+		options:     options,
+		handler:     handler,
+		writeLocker: &sync.Mutex{},
+		locker:      &sync.Mutex{},
+		done:        make(chan byte),
+		closeOnce:   &sync.Once{},
+	}
+	go c.readLoop()
+	if options.PingInterval > 0 {
+		go c.pingLoop()
+	}
+	return c, nil
+}
+
+func setQuery(query url.Values, key string, value string) {
+	if value != "" {
+		query.Set(key, value)
+	}
+}
+
+//Done is closed when the connection is gone, Err tells why
+func (c *Client) Done() <-chan byte {
+	return c.done
+}
+
+func (c *Client) Err() error {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	return c.err
+}
+
+func (c *Client) Close() error {
+	c.fail(ErrClosed)
+	return nil
+}
+
+func (c *Client) fail(e error) {
+	c.closeOnce.Do(func() {
+		c.locker.Lock()
+		c.err = e
+		c.locker.Unlock()
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+//Latency is the last round trip time of a ping sent by the client
+func (c *Client) Latency() time.Duration {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	return c.latency
+}
+
+//Move steers towards x,y relative to the player and returns the sequence number of the input
+func (c *Client) Move(x, y float64) (int64, error) {
+	c.locker.Lock()
+	c.seq++
+	seq := c.seq
+	c.locker.Unlock()
+	m := &protocol.Move{X: int(x), Y: int(y), Seq: seq}
+	return seq, c.Send(protocol.ActionMove, m.Encode())
+}
+
+func (c *Client) Fire() error {
+	return c.Send(protocol.ActionFire, "")
+}
+
+func (c *Client) Split() error {
+	return c.Send(protocol.ActionSplit, "")
+}
+
+//Respawn asks for a new player after a death, the server ignores it before GameSetup.RespawnDelay has passed
+func (c *Client) Respawn() error {
+	return c.Send(protocol.ActionRespawn, "")
+}
+
+func (c *Client) Say(msg string) error {
+	return c.Send(protocol.ActionChat, msg)
+}
+
+//Send writes a raw message, the typed helpers should be preferred
+func (c *Client) Send(action string, payload string) error {
+	select {
+	case <-c.done:
+		return c.Err()
+	default:
+	}
+	c.writeLocker.Lock()
+	defer c.writeLocker.Unlock()
+	if c.options.WriteTimeout > 0 {
+		if e := c.conn.SetWriteDeadline(time.Now().Add(c.options.WriteTimeout)); e != nil {
+			c.fail(e)
+			return e
+		}
+	}
+	if e := c.conn.WriteMessage(websocket.TextMessage, []byte(protocol.Encode(action, payload))); e != nil {
+		c.fail(e)
+		return e
+	}
+	return nil
+}
+
+func (c *Client) pingLoop() {
+	ticker := time.NewTicker(c.options.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.locker.Lock()
+			c.pingSent = time.Now()
+			c.locker.Unlock()
+			if c.Send(protocol.ActionPing, "") != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *Client) readLoop() {
+	for {
+		_, data, e := c.conn.ReadMessage()
+		if e != nil {
+			c.fail(e)
+			return
+		}
+		action, payload, ok := protocol.Decode(string(data))
+		if !ok {
+			continue
+		}
+		if c.handler.Message != nil {
+			c.handler.Message(action, len(data))
+		}
+		c.dispatch(action, payload)
+	}
+}
+
+func (c *Client) dispatch(action string, payload string) {
+	h := c.handler
+	switch action {
+	case protocol.ActionError:
+		if h.Error != nil {
+			e, err := protocol.ParseError(payload)
+			if err != nil {
+				c.malformed(action, err)
+				return
+			}
+			h.Error(e)
+		}
+	case protocol.ActionPing:
+		c.ping(payload)
+	case protocol.ActionGameSetup:
+		if h.GameSetup != nil {
+			setup, e := protocol.ParseGameSetup(payload)
+			if e != nil {
+				c.malformed(action, e)
+				return
+			}
+			h.GameSetup(setup)
+		}
+	case protocol.ActionChat:
+		if h.Chat != nil {
+			chat, e := protocol.ParseChat(payload)
+			if e != nil {
+				c.malformed(action, e)
+				return
+			}
+			h.Chat(chat)
+		}
+	case protocol.ActionPlayerStatus:
+		if h.PlayerStatus != nil {
+			status, e := protocol.ParsePlayerStatus(payload)
+			if e != nil {
+				c.malformed(action, e)
+				return
+			}
+			h.PlayerStatus(status)
+		}
+	case protocol.ActionDeath:
+		if h.Death != nil {
+			death, e := protocol.ParseDeath(payload)
+			if e != nil {
+				c.malformed(action, e)
+				return
+			}
+			h.Death(death)
+		}
+	case protocol.ActionKill:
+		if h.Kill != nil {
+			kill, e := protocol.ParseKill(payload)
+			if e != nil {
+				c.malformed(action, e)
+				return
+			}
+			h.Kill(kill)
+		}
+	case protocol.ActionAchievement:
+		if h.Achievement != nil {
+			achievement, e := protocol.ParseAchievement(payload)
+			if e != nil {
+				c.malformed(action, e)
+				return
+			}
+			h.Achievement(achievement)
+		}
+	case protocol.ActionLeaderBoard:
+		if h.LeaderBoard != nil {
+			leaderBoard, e := protocol.ParseLeaderBoard(payload)
+			if e != nil {
+				c.malformed(action, e)
+				return
+			}
+			h.LeaderBoard(leaderBoard)
+		}
+	}
+}
+
+//an empty payload answers our own ping, otherwise the server measures its latency and wants it back
+func (c *Client) ping(payload string) {
+	if payload != "" {
+		c.Send(protocol.ActionPing, payload)
+		return
+	}
+	c.locker.Lock()
+	if !c.pingSent.IsZero() {
+		c.latency = time.Since(c.pingSent)
+		c.pingSent = time.Time{}
+	}
+	c.locker.Unlock()
+}
+
+func (c *Client) malformed(action string, e error) {
+	if c.handler.Malformed != nil {
+		c.handler.Malformed(action, e)
+	}
+}
+
+//ServerTime converts a millisecond timestamp of the protocol
+func ServerTime(millis int64) time.Time {
+	return time.Unix(0, millis*int64(time.Millisecond))
+}
@@ -0,0 +1,22 @@
+package protocol
+
+import "strings"
+
+//Achievement is sent to the player that unlocked it
+type Achievement struct {
+	Id          string
+	Name        string
+	Description string
+}
+
+func (a *Achievement) Encode() string {
+	return a.Id + "|" + a.Name + "|" + a.Description
+}
+
+func ParseAchievement(payload string) (*Achievement, error) {
+	parts := strings.SplitN(payload, "|", 3)
+	if len(parts) < 3 {
+		return nil, ErrMalformed
+	}
+	return &Achievement{Id: parts[0], Name: parts[1], Description: parts[2]}, nil
+}
@@ -1,4 +1,4 @@
-package gateway
+package protocol
 
 const (
 	ChatTypeSystem = "0"
@@ -23,3 +23,17 @@ func NewPlayerChat(data string) *Chat {
 		Data: data,
 	}
 }
+
+func (c *Chat) Encode() string {
+	return c.Type + c.Data
+}
+
+func ParseChat(payload string) (*Chat, error) {
+	if payload == "" {
+		return nil, ErrMalformed
+	}
+	return &Chat{
+		Type: payload[:1],
+		Data: payload[1:],
+	}, nil
+}
@@ -0,0 +1,66 @@
+package protocol
+
+import (
+	"strconv"
+	"strings"
+)
+
+//Death is sent once when the player is eaten, TimeAlive is in milliseconds and Killer is empty if nobody ate it
+type Death struct {
+	Killer     string
+	PeakMass   float64
+	TimeAlive  int64
+	FoodsEaten int
+	CellsEaten int
+}
+
+//Encode puts the killer last, so a name is never split
+func (d *Death) Encode() string {
+	return strings.Join([]string{
+		formatFloat(d.PeakMass, 0),
+		strconv.FormatInt(d.TimeAlive, 10),
+		strconv.Itoa(d.FoodsEaten),
+		strconv.Itoa(d.CellsEaten),
+		d.Killer,
+	}, "|")
+}
+
+func ParseDeath(payload string) (*Death, error) {
+	parts := strings.SplitN(payload, "|", 5)
+	if len(parts) < 5 {
+		return nil, ErrMalformed
+	}
+	d := &Death{Killer: parts[4]}
+	var e error
+	if d.PeakMass, e = strconv.ParseFloat(parts[0], 64); e != nil {
+		return nil, ErrMalformed
+	}
+	if d.TimeAlive, e = strconv.ParseInt(parts[1], 10, 64); e != nil {
+		return nil, ErrMalformed
+	}
+	if d.FoodsEaten, e = strconv.Atoi(parts[2]); e != nil {
+		return nil, ErrMalformed
+	}
+	if d.CellsEaten, e = strconv.Atoi(parts[3]); e != nil {
+		return nil, ErrMalformed
+	}
+	return d, nil
+}
+
+//Kill is an entry of the kill feed, pushed to everyone in the battle
+type Kill struct {
+	Killer string
+	Victim string
+}
+
+func (k *Kill) Encode() string {
+	return k.Killer + "|" + k.Victim
+}
+
+func ParseKill(payload string) (*Kill, error) {
+	parts := strings.SplitN(payload, "|", 2)
+	if len(parts) < 2 {
+		return nil, ErrMalformed
+	}
+	return &Kill{Killer: parts[0], Victim: parts[1]}, nil
+}
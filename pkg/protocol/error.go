@@ -0,0 +1,78 @@
+package protocol
+
+import (
+	"strconv"
+	"strings"
+)
+
+//Version is announced by clients with the v query parameter of the game endpoint
+const Version = 1
+
+type ErrorCode int
+
+//codes sent with ActionError, the connection is closed right after
+const (
+	ErrorUnknown ErrorCode = iota
+	ErrorBattleFull
+	ErrorRoomNotFound
+	ErrorInvalidName
+	ErrorNameTaken
+	ErrorRateLimited
+	ErrorBanned
+	ErrorKicked
+	ErrorShuttingDown
+	ErrorVersionMismatch
+	ErrorUnavailable
+)
+
+var errorMessages = map[ErrorCode]string{
+	ErrorUnknown:         "unknown error",
+	ErrorBattleFull:      "battle is full",
+	ErrorRoomNotFound:    "room not found",
+	ErrorInvalidName:     "name is not allowed",
+	ErrorNameTaken:       "name is taken",
+	ErrorRateLimited:     "too many requests",
+	ErrorBanned:          "banned",
+	ErrorKicked:          "kicked",
+	ErrorShuttingDown:    "server is shutting down",
+	ErrorVersionMismatch: "protocol version mismatch",
+	ErrorUnavailable:     "no battle server available",
+}
+
+//Error is encoded as "code|message"
+type Error struct {
+	Code    ErrorCode
+	Message string
+}
+
+//NewError uses the default message of the code when message is empty
+func NewError(code ErrorCode, message string) *Error {
+	if message == "" {
+		message = errorMessages[code]
+	}
+	return &Error{
+		Code:    code,
+		Message: message,
+	}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Encode() string {
+	return strconv.Itoa(int(e.Code)) + "|" + e.Message
+}
+
+//ParseError keeps a payload without code as the message of an unknown error
+func ParseError(payload string) (*Error, error) {
+	i := strings.IndexByte(payload, '|')
+	if i < 0 {
+		return NewError(ErrorUnknown, payload), nil
+	}
+	code, e := strconv.Atoi(payload[:i])
+	if e != nil {
+		return nil, ErrMalformed
+	}
+	return NewError(ErrorCode(code), payload[i+1:]), nil
+}
@@ -0,0 +1,178 @@
+//Package protocol is the text protocol spoken between the game server and its clients,
+//every message is a two digit action, a "|" and the payload of that action
+package protocol
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	ActionError        = "00"
+	ActionPing         = "01"
+	ActionGameSetup    = "02"
+	ActionChat         = "03"
+	ActionPlayerStatus = "04"
+	ActionMove         = "05"
+	ActionFire         = "06"
+	ActionSplit        = "07"
+	ActionLeaderBoard  = "08"
+	ActionDeath        = "09"
+	ActionRespawn      = "10"
+	ActionKill         = "11"
+	ActionAchievement  = "12"
+)
+
+var ErrMalformed = errors.New("malformed payload")
+
+func Encode(action string, payload string) string {
+	return action + "|" + payload
+}
+
+//Decode splits a message into its action and payload
+func Decode(msg string) (string, string, bool) {
+	if len(msg) < 2 {
+		return "", "", false
+	}
+	payload := ""
+	if len(msg) > 3 {
+		payload = msg[3:]
+	}
+	return msg[:2], payload, true
+}
+
+type GameSetup struct {
+	Width        float64
+	Height       float64
+	ScreenWidth  float64
+	ScreenHeight float64
+	VirusColor   string
+	RoomName     string
+	RoomCode     string
+	//RespawnDelay is how many milliseconds after a death the server accepts ActionRespawn
+	RespawnDelay int64
+	//TickRate is how many times a second the server reads input, moves sent faster are wasted
+	TickRate int
+}
+
+func (s *GameSetup) Encode() string {
+	return strings.Join([]string{
+		formatFloat(s.Width, 0),
+		formatFloat(s.Height, 0),
+		formatFloat(s.ScreenWidth, 0),
+		formatFloat(s.ScreenHeight, 0),
+		s.VirusColor,
+		s.RoomName,
+		s.RoomCode,
+		strconv.FormatInt(s.RespawnDelay, 10),
+		strconv.Itoa(s.TickRate),
+	}, "|")
+}
+
+func ParseGameSetup(payload string) (*GameSetup, error) {
+	parts := strings.Split(payload, "|")
+	if len(parts) < 7 {
+		return nil, ErrMalformed
+	}
+	s := &GameSetup{
+		VirusColor: parts[4],
+		RoomName:   parts[5],
+		RoomCode:   parts[6],
+	}
+	var e error
+	if s.Width, e = strconv.ParseFloat(parts[0], 64); e != nil {
+		return nil, ErrMalformed
+	}
+	if s.Height, e = strconv.ParseFloat(parts[1], 64); e != nil {
+		return nil, ErrMalformed
+	}
+	if s.ScreenWidth, e = strconv.ParseFloat(parts[2], 64); e != nil {
+		return nil, ErrMalformed
+	}
+	if s.ScreenHeight, e = strconv.ParseFloat(parts[3], 64); e != nil {
+		return nil, ErrMalformed
+	}
+	//older servers do not send the respawn delay
+	if len(parts) > 7 {
+		if s.RespawnDelay, e = strconv.ParseInt(parts[7], 10, 64); e != nil {
+			return nil, ErrMalformed
+		}
+	}
+	if len(parts) > 8 {
+		if s.TickRate, e = strconv.Atoi(parts[8]); e != nil {
+			return nil, ErrMalformed
+		}
+	}
+	return s, nil
+}
+
+//LeaderBoard carries the names ordered by mass, Time is in unix milliseconds
+type LeaderBoard struct {
+	Tick  uint64
+	Time  int64
+	Names []string
+}
+
+func (l *LeaderBoard) Encode() string {
+	return strconv.FormatUint(l.Tick, 10) + "|" + strconv.FormatInt(l.Time, 10) + "|" + strings.Join(l.Names, ",")
+}
+
+func ParseLeaderBoard(payload string) (*LeaderBoard, error) {
+	parts := strings.SplitN(payload, "|", 3)
+	if len(parts) < 3 {
+		return nil, ErrMalformed
+	}
+	l := &LeaderBoard{}
+	var e error
+	if l.Tick, e = strconv.ParseUint(parts[0], 10, 64); e != nil {
+		return nil, ErrMalformed
+	}
+	if l.Time, e = strconv.ParseInt(parts[1], 10, 64); e != nil {
+		return nil, ErrMalformed
+	}
+	if parts[2] != "" {
+		l.Names = strings.Split(parts[2], ",")
+	}
+	return l, nil
+}
+
+//Move is a target relative to the center of the player, Seq is 0 for clients without prediction
+type Move struct {
+	X   int
+	Y   int
+	Seq int64
+}
+
+func (m *Move) Encode() string {
+	payload := strconv.Itoa(m.X) + "," + strconv.Itoa(m.Y)
+	if m.Seq != 0 {
+		payload += "," + strconv.FormatInt(m.Seq, 10)
+	}
+	return payload
+}
+
+func ParseMove(payload string) (*Move, error) {
+	split := strings.Split(payload, ",")
+	if len(split) < 2 {
+		return nil, ErrMalformed
+	}
+	m := &Move{}
+	var e error
+	if m.X, e = strconv.Atoi(split[0]); e != nil {
+		return nil, ErrMalformed
+	}
+	if m.Y, e = strconv.Atoi(split[1]); e != nil {
+		return nil, ErrMalformed
+	}
+	if len(split) > 2 {
+		if m.Seq, e = strconv.ParseInt(split[2], 10, 64); e != nil {
+			return nil, ErrMalformed
+		}
+	}
+	return m, nil
+}
+
+func formatFloat(f float64, prec int) string {
+	return strconv.FormatFloat(f, 'f', prec, 64)
+}
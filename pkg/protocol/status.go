@@ -0,0 +1,261 @@
+package protocol
+
+import (
+	"strconv"
+	"strings"
+)
+
+//PlayerStatus is what a player sees each tick, Time is the server time in unix milliseconds
+type PlayerStatus struct {
+	Name      string
+	X         float64
+	Y         float64
+	MassTotal float64
+	LastInput int64
+	Tick      uint64
+	Time      int64
+	Cells     []Cell
+	Foods     []Food
+	MassFoods []MassFood
+	Viruses   []Virus
+}
+
+type Cell struct {
+	Name      string
+	Color     string
+	TextColor string
+	X         float64
+	Y         float64
+	Radius    float64
+	Id        uint64
+	VX        float64
+	VY        float64
+	Mine      bool
+	Skin      string
+}
+
+type Food struct {
+	X      float64
+	Y      float64
+	Radius float64
+	Color  string
+}
+
+type MassFood struct {
+	X      float64
+	Y      float64
+	Radius float64
+	Color  string
+}
+
+type Virus struct {
+	X      float64
+	Y      float64
+	Radius float64
+}
+
+const (
+	statusHeadFields = 7
+	cellFields       = 11
+	foodFields       = 4
+	virusFields      = 3
+)
+
+func (p *PlayerStatus) Encode() string {
+	var b strings.Builder
+	b.WriteString(p.Name)
+	b.WriteByte(',')
+	writeFloats(&b, 2, p.X, p.Y, p.MassTotal)
+	b.WriteByte(',')
+	b.WriteString(strconv.FormatInt(p.LastInput, 10))
+	b.WriteByte(',')
+	b.WriteString(strconv.FormatUint(p.Tick, 10))
+	b.WriteByte(',')
+	b.WriteString(strconv.FormatInt(p.Time, 10))
+	writeCount(&b, len(p.Cells))
+	for _, c := range p.Cells {
+		b.WriteByte('|')
+		b.WriteString(c.Name)
+		b.WriteByte(',')
+		b.WriteString(c.Color)
+		b.WriteByte(',')
+		b.WriteString(c.TextColor)
+		b.WriteByte(',')
+		writeFloats(&b, 2, c.X, c.Y, c.Radius)
+		b.WriteByte(',')
+		b.WriteString(strconv.FormatUint(c.Id, 10))
+		b.WriteByte(',')
+		writeFloats(&b, 1, c.VX, c.VY)
+		if c.Mine {
+			b.WriteString(",1,")
+		} else {
+			b.WriteString(",0,")
+		}
+		b.WriteString(c.Skin)
+	}
+	writeCount(&b, len(p.Foods))
+	for _, f := range p.Foods {
+		b.WriteByte('|')
+		writeFloats(&b, 2, f.X, f.Y, f.Radius)
+		b.WriteByte(',')
+		b.WriteString(f.Color)
+	}
+	writeCount(&b, len(p.MassFoods))
+	for _, f := range p.MassFoods {
+		b.WriteByte('|')
+		writeFloats(&b, 2, f.X, f.Y, f.Radius)
+		b.WriteByte(',')
+		b.WriteString(f.Color)
+	}
+	writeCount(&b, len(p.Viruses))
+	for _, v := range p.Viruses {
+		b.WriteByte('|')
+		writeFloats(&b, 2, v.X, v.Y, v.Radius)
+	}
+	return b.String()
+}
+
+func ParsePlayerStatus(payload string) (*PlayerStatus, error) {
+	r := &statusReader{parts: strings.Split(payload, "|")}
+	head := r.fields(statusHeadFields)
+	if head == nil {
+		return nil, ErrMalformed
+	}
+	p := &PlayerStatus{Name: head[0]}
+	p.X = r.float(head[1])
+	p.Y = r.float(head[2])
+	p.MassTotal = r.float(head[3])
+	p.LastInput = r.int(head[4])
+	p.Tick = r.uint(head[5])
+	p.Time = r.int(head[6])
+	n := r.count()
+	p.Cells = make([]Cell, 0, n)
+	for i := 0; i < n; i++ {
+		f := r.fields(cellFields)
+		if f == nil {
+			return nil, ErrMalformed
+		}
+		p.Cells = append(p.Cells, Cell{
+			Name:      f[0],
+			Color:     f[1],
+			TextColor: f[2],
+			X:         r.float(f[3]),
+			Y:         r.float(f[4]),
+			Radius:    r.float(f[5]),
+			Id:        r.uint(f[6]),
+			VX:        r.float(f[7]),
+			VY:        r.float(f[8]),
+			Mine:      f[9] == "1",
+			Skin:      f[10],
+		})
+	}
+	n = r.count()
+	p.Foods = make([]Food, 0, n)
+	for i := 0; i < n; i++ {
+		f := r.fields(foodFields)
+		if f == nil {
+			return nil, ErrMalformed
+		}
+		p.Foods = append(p.Foods, Food{X: r.float(f[0]), Y: r.float(f[1]), Radius: r.float(f[2]), Color: f[3]})
+	}
+	n = r.count()
+	p.MassFoods = make([]MassFood, 0, n)
+	for i := 0; i < n; i++ {
+		f := r.fields(foodFields)
+		if f == nil {
+			return nil, ErrMalformed
+		}
+		p.MassFoods = append(p.MassFoods, MassFood{X: r.float(f[0]), Y: r.float(f[1]), Radius: r.float(f[2]), Color: f[3]})
+	}
+	n = r.count()
+	p.Viruses = make([]Virus, 0, n)
+	for i := 0; i < n; i++ {
+		f := r.fields(virusFields)
+		if f == nil {
+			return nil, ErrMalformed
+		}
+		p.Viruses = append(p.Viruses, Virus{X: r.float(f[0]), Y: r.float(f[1]), Radius: r.float(f[2])})
+	}
+	if r.err {
+		return nil, ErrMalformed
+	}
+	return p, nil
+}
+
+//statusReader walks the "|" separated sections and remembers the first bad value
+type statusReader struct {
+	parts []string
+	next  int
+	err   bool
+}
+
+//fields returns the next section split by ",", a name in front may itself contain commas
+func (r *statusReader) fields(n int) []string {
+	if r.err || r.next >= len(r.parts) {
+		r.err = true
+		return nil
+	}
+	f := strings.Split(r.parts[r.next], ",")
+	r.next++
+	if len(f) < n {
+		r.err = true
+		return nil
+	}
+	if len(f) > n {
+		extra := len(f) - n
+		f = append([]string{strings.Join(f[:extra+1], ",")}, f[extra+1:]...)
+	}
+	return f
+}
+
+func (r *statusReader) count() int {
+	if r.err || r.next >= len(r.parts) {
+		r.err = true
+		return 0
+	}
+	n, e := strconv.Atoi(r.parts[r.next])
+	r.next++
+	if e != nil || n < 0 || n > len(r.parts)-r.next {
+		r.err = true
+		return 0
+	}
+	return n
+}
+
+func (r *statusReader) float(s string) float64 {
+	f, e := strconv.ParseFloat(s, 64)
+	if e != nil {
+		r.err = true
+	}
+	return f
+}
+
+func (r *statusReader) int(s string) int64 {
+	i, e := strconv.ParseInt(s, 10, 64)
+	if e != nil {
+		r.err = true
+	}
+	return i
+}
+
+func (r *statusReader) uint(s string) uint64 {
+	i, e := strconv.ParseUint(s, 10, 64)
+	if e != nil {
+		r.err = true
+	}
+	return i
+}
+
+func writeFloats(b *strings.Builder, prec int, values ...float64) {
+	for i, v := range values {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(v, 'f', prec, 64))
+	}
+}
+
+func writeCount(b *strings.Builder, n int) {
+	b.WriteByte('|')
+	b.WriteString(strconv.Itoa(n))
+}
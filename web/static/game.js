@@ -0,0 +1,896 @@
+const ActionError = "00",
+    ActionPing = "01",
+    ActionGameSetup = "02",
+    ActionChat = "03",
+    ActionPlayerStatus = "04",
+    ActionMove = "05",
+    ActionFire = "06",
+    ActionSplit = "07",
+    ActionLeaderBoard = "08",
+    ActionDeath = "09",
+    ActionRespawn = "10",
+    ActionKill = "11",
+    ActionAchievement = "12";
+
+const ProtocolVersion = 1;
+
+//hints for the codes sent with ActionError, other codes show the message of the server
+const errorHints = {
+    1: 'the battle is full, try another room',
+    2: 'the room does not exist any more',
+    3: 'choose another name',
+    4: 'the name is taken, choose another one',
+    5: 'too many connections or messages, slow down',
+    8: 'the server is restarting, come back soon',
+    9: 'the game was updated, reload the page',
+    10: 'no game server is available, try again later',
+};
+
+const global = {
+    debug: false,
+    gameWidth: 0,
+    gameHeight: 0,
+    screenWidth: window.innerWidth,
+    screenHeight: window.innerHeight,
+    lineColor: '#000000',
+    backgroundColor: '#f2fbff',
+    virusColor: '#7bff66',
+    roomName: '',
+    respawnDelay: 0,
+    tickRate: 60,
+    killFeedSize: 5,
+    killFeedSeconds: 6,
+    achievementSeconds: 5,
+    roomCode: '',
+    ripWaitSeconds: 3,
+};
+
+const client = {
+    targetX: 0,
+    targetY: 0,
+    player: undefined,
+    ws: undefined,
+    pingTime: undefined,
+    ping: undefined,
+    animLoopHandle: undefined,
+    gameLoopCount: 0,
+    inputSeq: 0,
+    moveTime: 0,
+    skippedTicks: 0,
+    error: undefined,
+    death: undefined,
+    kills: [],
+    achievements: [],
+};
+
+const canvas = document.getElementById("game"),
+    graph = canvas.getContext("2d");
+
+const controller = {
+    init() {
+        document.getElementById('startBtn').onclick = controller.start;
+        window.onkeypress = controller.onKeypress;
+        canvas.addEventListener("mousemove", controller.onMouseMove);
+        canvas.addEventListener("mouseout", controller.onMouseOut);
+        canvas.addEventListener("click", controller.onClick);
+        window.addEventListener("keydown", controller.onKeydown);
+
+        window.requestAnimFrame = (function () {
+            return window.requestAnimationFrame ||
+                window.webkitRequestAnimationFrame ||
+                window.mozRequestAnimationFrame ||
+                window.msRequestAnimationFrame ||
+                window.oRequestAnimationFrame ||
+                window.msRequestAnimationFrame ||
+                function (callback) {
+                    window.setTimeout(callback, 1000 / 60);
+                };
+        })();
+
+        window.cancelAnimFrame = (function (handle) {
+            return window.cancelAnimationFrame ||
+                window.mozCancelAnimationFrame;
+        })();
+    },
+    start() {
+        let name = document.getElementById('playerNameInput').value;
+        if (name === '') {
+            return
+        }
+        let protocol = window.location.protocol === 'https:' ? 'wss' : 'ws';
+        let ws = new WebSocket(`${protocol}://${window.location.host}/game?v=${ProtocolVersion}&name=${encodeURIComponent(name)}${lobby.query()}`);
+        ws.onopen = evt => {
+            document.getElementById('gameAreaWrapper').style.opacity = 1;
+            document.getElementById('startMenuWrapper').style.maxHeight = '0px';
+
+            client.ws = ws;
+            controller.gameLoop();
+        };
+        ws.onmessage = evt => handler.handle(evt.data);
+        ws.onclose = evt => {
+            //the death screen was already shown
+            let wait = client.death && !client.error ? 0 : global.ripWaitSeconds;
+            client.ws = undefined;
+            client.player = undefined;
+            client.death = undefined;
+            client.kills = [];
+            client.achievements = [];
+            predictor.reset();
+            drawer.drawBackground();
+            if (wait > 0) {
+                drawer.drawRIP();
+            }
+            if (client.animLoopHandle) {
+                window.cancelAnimationFrame(client.animLoopHandle);
+                client.animLoopHandle = undefined;
+            }
+            window.setTimeout(function () {
+                client.error = undefined;
+                document.getElementById('gameAreaWrapper').style.opacity = 0;
+                document.getElementById('startMenuWrapper').style.maxHeight = '1000px';
+                messager.clear();
+                lobby.refresh();
+            }, wait * 1000);
+        };
+    },
+    gameLoop() {
+        if (!client.ws) {
+            return
+        }
+        if (client.gameLoopCount > 60) {
+            client.pingTime = (new Date()).valueOf();
+            sender.ping();
+            client.gameLoopCount = 0;
+        } else {
+            client.gameLoopCount++;
+        }
+        client.animLoopHandle = window.requestAnimFrame(controller.gameLoop);
+        drawer.drawBackground();
+        let player = client.player;
+        if (client.death) {
+            drawer.drawDeath();
+        } else if (player) {
+            drawer.drawPlayer();
+            //fast displays draw several frames per server tick, one move per tick is all the server reads
+            let now = performance.now();
+            if (now - client.moveTime >= 900 / global.tickRate) {
+                client.moveTime = now;
+                client.inputSeq++;
+                predictor.input(client.inputSeq);
+                sender.send(ActionMove, client.targetX + ',' + client.targetY + ',' + client.inputSeq)
+            }
+        }
+        drawer.drawKillFeed();
+        drawer.drawAchievements();
+        if (global.debug) {
+            drawer.drawDebugInfo();
+        }
+    },
+    onKeypress(event) {
+        let key = event.which || event.keyCode;
+        switch (key) {
+            case 88:
+            case 122:
+                sender.send(ActionFire);
+                break;
+            case 90:
+            case 120:
+                sender.send(ActionSplit);
+                break;
+        }
+    },
+    onMouseMove(event) {
+        let cRect = canvas.getBoundingClientRect();
+        let x = Math.round(event.clientX - cRect.left);
+        let y = Math.round(event.clientY - cRect.top);
+        client.targetX = x - global.screenWidth / 2;
+        client.targetY = y - global.screenHeight / 2;
+    },
+    onMouseOut(event) {
+        client.targetX = 0;
+        client.targetY = 0;
+    },
+    onClick(event) {
+        controller.respawn();
+    },
+    onKeydown(event) {
+        if (!client.death || event.target === messager.chatInput) {
+            return;
+        }
+        let key = event.which || event.keyCode;
+        if (key === 13) {
+            controller.respawn();
+        } else if (key === 27 && client.ws) {
+            client.ws.close();
+        }
+    },
+    respawn() {
+        let death = client.death;
+        if (!death || death.respawning || Date.now() < death.respawnAt) {
+            return;
+        }
+        death.respawning = true;
+        sender.send(ActionRespawn);
+    },
+};
+
+const lobby = {
+    roomSelect: document.getElementById('roomSelect'),
+    roomCodeInput: document.getElementById('roomCodeInput'),
+    roomCreateInput: document.getElementById('roomCreateInput'),
+    roomPrivateInput: document.getElementById('roomPrivateInput'),
+    colorSelect: document.getElementById('colorSelect'),
+    skinSelect: document.getElementById('skinSelect'),
+    init() {
+        lobby.roomSelect.addEventListener('focus', lobby.refresh);
+        lobby.colorSelect.addEventListener('change', () => {
+            lobby.colorSelect.style.color = lobby.colorSelect.value;
+        });
+        lobby.refresh();
+        lobby.loadLooks();
+    },
+    loadLooks() {
+        fetch('/looks').then(resp => resp.json()).then(looks => {
+            looks.colors.forEach(color => {
+                let option = document.createElement('option');
+                option.value = color;
+                option.text = '● ' + color;
+                option.style.color = color;
+                lobby.colorSelect.add(option);
+            });
+            looks.skins.forEach(skin => {
+                let option = document.createElement('option');
+                option.value = skin;
+                option.text = skin.replace(/\.[^.]+$/, '');
+                lobby.skinSelect.add(option);
+            });
+        });
+    },
+    refresh() {
+        fetch('/rooms').then(resp => resp.json()).then(rooms => {
+            let select = lobby.roomSelect;
+            let selected = select.value;
+            select.options.length = 1;
+            rooms.forEach(room => {
+                let option = document.createElement('option');
+                option.value = room.id;
+                option.text = `${room.name} (${room.players}/${room.limit})`;
+                select.add(option);
+            });
+            select.value = selected;
+            if (select.selectedIndex < 0) {
+                select.selectedIndex = 0;
+            }
+        });
+    },
+    query() {
+        let query = '';
+        if (lobby.colorSelect.value !== '') {
+            query += `&color=${encodeURIComponent(lobby.colorSelect.value)}`;
+        }
+        if (lobby.skinSelect.value !== '') {
+            query += `&skin=${encodeURIComponent(lobby.skinSelect.value)}`;
+        }
+        return query + lobby.roomQuery();
+    },
+    roomQuery() {
+        let create = lobby.roomCreateInput.value.trim();
+        if (create !== '' || lobby.roomPrivateInput.checked) {
+            return `&create=${encodeURIComponent(create)}&private=${lobby.roomPrivateInput.checked ? 1 : 0}`;
+        }
+        let code = lobby.roomCodeInput.value.trim();
+        if (code !== '') {
+            return `&code=${encodeURIComponent(code)}`;
+        }
+        let room = lobby.roomSelect.value;
+        if (room !== '') {
+            return `&room=${encodeURIComponent(room)}`;
+        }
+        return '';
+    },
+};
+
+const messager = {
+    chatInput: document.getElementById('chatInput'),
+    chatList: document.getElementById('chatList'),
+    init() {
+        let input = messager.chatInput;
+        input.addEventListener('keypress', messager.onKeypress);
+        input.addEventListener('keyup', messager.onKeyup);
+    },
+    onKeypress(event) {
+        let input = messager.chatInput;
+        let key = event.which || event.keyCode;
+        if (key === 13 || key === 108) {
+            let text = input.value.replace(/(<([^>]+)>)/ig, '');
+            if (text !== '') {
+                sender.send(ActionChat, text);
+                input.value = '';
+                canvas.focus();
+            }
+        }
+    },
+    onKeyup(event) {
+        let input = messager.chatInput;
+        let key = event.which || event.keyCode;
+        if (key === 27) {
+            input.value = '';
+            canvas.focus();
+        }
+    },
+    append(message, isSystem) {
+        let line = document.createElement('li');
+        if (isSystem) {
+            line.className = 'player';
+        } else {
+            line.className = 'system';
+        }
+        line.innerHTML = message;
+        let chatList = messager.chatList;
+        if (chatList.childNodes.length > 10) {
+            chatList.removeChild(chatList.childNodes[0]);
+        }
+        chatList.appendChild(line);
+    },
+    clear() {
+        messager.chatList.innerHTML = ''
+    },
+};
+
+// own cells are extrapolated from the latest status towards the current input and
+// corrected smoothly when the server disagrees, other cells are interpolated between
+// the two status frames around (now - interpDelay), all on the server clock
+const predictor = {
+    interpDelay: 100,
+    maxExtrapolate: 100,
+    correctionTime: 150,
+    snapshots: [],
+    pending: [],
+    corrections: {},
+    offset: undefined,
+    reset() {
+        predictor.snapshots = [];
+        predictor.pending = [];
+        predictor.corrections = {};
+        predictor.offset = undefined;
+        client.skippedTicks = 0;
+    },
+    // the least delayed frame gives the best offset, it decays slowly to follow clock drift
+    syncClock(serverTime) {
+        let offset = serverTime - performance.now();
+        if (predictor.offset === undefined || offset > predictor.offset - 1) {
+            predictor.offset = offset;
+        } else {
+            predictor.offset -= 1;
+        }
+    },
+    serverNow() {
+        return performance.now() + (predictor.offset || 0);
+    },
+    input(seq) {
+        predictor.pending.push({seq: seq, time: performance.now()});
+    },
+    push(player) {
+        let latestTick = predictor.latest() ? predictor.latest().player.tick : undefined;
+        if (latestTick !== undefined && player.tick <= latestTick) {
+            return
+        }
+        if (latestTick !== undefined && player.tick > latestTick + 1) {
+            client.skippedTicks += player.tick - latestTick - 1;
+        }
+        predictor.syncClock(player.serverTime);
+        let now = predictor.serverNow();
+        for (let id in predictor.corrections) {
+            if (now - predictor.corrections[id].time > predictor.correctionTime) {
+                delete predictor.corrections[id];
+            }
+        }
+        let latest = predictor.latest();
+        if (latest) {
+            player.visibleCells.forEach(cell => {
+                if (!cell.mine) {
+                    return
+                }
+                let old = latest.player.visibleCells.find(item => item.id === cell.id);
+                if (!old) {
+                    return
+                }
+                let shown = predictor.predictOwn(old, latest, now);
+                predictor.corrections[cell.id] = {x: shown.x - cell.x, y: shown.y - cell.y, time: now};
+            });
+        }
+        predictor.pending = predictor.pending.filter(input => input.seq > player.lastInput && now - input.time < 1000);
+        let snapshots = predictor.snapshots;
+        snapshots.push({time: player.serverTime, player: player});
+        while (snapshots.length > 2 && snapshots[1].time < now - predictor.interpDelay) {
+            snapshots.shift();
+        }
+    },
+    latest() {
+        let snapshots = predictor.snapshots;
+        return snapshots[snapshots.length - 1];
+    },
+    predictOwn(cell, snapshot, now) {
+        let dt = Math.min(now - snapshot.time, predictor.maxExtrapolate) / 1000;
+        let vx = cell.vx, vy = cell.vy;
+        if (predictor.pending.length > 0) {
+            let speed = Math.hypot(vx, vy);
+            let dx = snapshot.player.x + client.targetX - cell.x;
+            let dy = snapshot.player.y + client.targetY - cell.y;
+            let dist = Math.hypot(dx, dy);
+            if (dist > 0) {
+                vx = speed * dx / dist;
+                vy = speed * dy / dist;
+            }
+        }
+        let x = cell.x + vx * dt, y = cell.y + vy * dt;
+        let correction = predictor.corrections[cell.id];
+        if (correction) {
+            let fade = 1 - (now - correction.time) / predictor.correctionTime;
+            if (fade > 0) {
+                x += correction.x * fade;
+                y += correction.y * fade;
+            }
+        }
+        return {x: x, y: y};
+    },
+    interpolate(cell, renderTime) {
+        let snapshots = predictor.snapshots;
+        for (let i = 0; i < snapshots.length - 1; i++) {
+            let from = snapshots[i], to = snapshots[i + 1];
+            if (from.time > renderTime || to.time < renderTime) {
+                continue
+            }
+            let a = from.player.visibleCells.find(item => item.id === cell.id);
+            let b = to.player.visibleCells.find(item => item.id === cell.id);
+            if (!a || !b) {
+                return cell
+            }
+            let t = (renderTime - from.time) / (to.time - from.time);
+            return Object.assign({}, cell, {
+                x: a.x + (b.x - a.x) * t,
+                y: a.y + (b.y - a.y) * t,
+                radius: a.radius + (b.radius - a.radius) * t,
+            });
+        }
+        return cell
+    },
+    frame() {
+        let now = predictor.serverNow();
+        let latest = predictor.latest();
+        let player = latest.player;
+        let renderTime = now - predictor.interpDelay;
+        let ownX = 0, ownY = 0, own = 0;
+        let cells = player.visibleCells.map(cell => {
+            if (!cell.mine) {
+                return predictor.interpolate(cell, renderTime)
+            }
+            let position = predictor.predictOwn(cell, latest, now);
+            ownX += position.x;
+            ownY += position.y;
+            own++;
+            return Object.assign({}, cell, position);
+        });
+        return Object.assign({}, player, {
+            x: own > 0 ? ownX / own : player.x,
+            y: own > 0 ? ownY / own : player.y,
+            visibleCells: cells,
+        });
+    },
+};
+
+const drawer = {
+    skins: {},
+    drawSkin(skin, x, y, radius) {
+        if (!skin) {
+            return;
+        }
+        let image = drawer.skins[skin];
+        if (!image) {
+            image = new Image();
+            image.src = `/skins/${encodeURIComponent(skin)}`;
+            drawer.skins[skin] = image;
+        }
+        if (!image.complete || image.naturalWidth === 0) {
+            return;
+        }
+        graph.save();
+        graph.beginPath();
+        graph.arc(x, y, radius, 0, 2 * Math.PI, false);
+        graph.closePath();
+        graph.clip();
+        graph.drawImage(image, x - radius, y - radius, radius * 2, radius * 2);
+        graph.restore();
+    },
+    drawBackground() {
+        graph.fillStyle = global.backgroundColor;
+        graph.fillRect(0, 0, global.screenWidth, global.screenHeight);
+    },
+    drawDebugInfo() {
+        let infos = [];
+        infos.push(['targetX:', client.targetX,
+            'targetY:', client.targetY,
+            'connected:', !!client.ws,
+            'ping', client.ping ? client.ping : 0,
+            'skipped ticks:', client.skippedTicks].join(' '));
+        graph.fillStyle = '#000000';
+        let player = client.player;
+        if (player) {
+            graph.fillStyle = '#000000';
+            infos.push(['x:', player.x,
+                'y:', player.y,
+                'mass:', player.massTotal].join(' '));
+            infos.push(['cell:', player.visibleCells.length,
+                'food:', player.visibleFoods.length,
+                'mass food:', player.visibleMassFoods.length,
+                'virus:', player.visibleViruses.length].join(' '));
+        }
+        for (let i = 0; i < infos.length; i++) {
+            graph.fillText(infos[i], 0, 10 + i * 20);
+        }
+    },
+    drawCircle(x, y, radius) {
+        graph.beginPath();
+        graph.arc(x, y, radius, 0, 2 * Math.PI, false);
+        graph.closePath();
+        graph.stroke();
+        graph.fill();
+    },
+    drawPlayer() {
+        let player = predictor.frame();
+        let font = graph.font;
+        player.visibleCells.forEach(item => {
+            let x = item.x - player.x + global.screenWidth / 2;
+            let y = item.y - player.y + global.screenHeight / 2;
+            let r = item.radius;
+            graph.fillStyle = item.background;
+            drawer.drawCircle(x, y, r);
+            drawer.drawSkin(item.skin, x, y, r);
+            graph.fillStyle = item.textColor;
+            let fontSize = Math.max(r / 3, 12);
+            graph.font = 'bold ' + fontSize + 'px sans-serif';
+            graph.fillText(item.name, x - fontSize * item.name.length / 2, y + 3);
+        });
+        graph.font = font;
+
+        player.visibleFoods.forEach(item => {
+            graph.fillStyle = item.color;
+            drawer.drawCircle(item.x - player.x + global.screenWidth / 2,
+                item.y - player.y + global.screenHeight / 2,
+                item.radius);
+        });
+
+        player.visibleMassFoods.forEach(item => {
+            graph.fillStyle = item.color;
+            drawer.drawCircle(item.x - player.x + global.screenWidth / 2,
+                item.y - player.y + global.screenHeight / 2,
+                item.radius);
+        });
+
+        graph.fillStyle = global.virusColor;
+        player.visibleViruses.forEach(item => {
+            drawer.drawCircle(item.x - player.x + global.screenWidth / 2,
+                item.y - player.y + global.screenHeight / 2,
+                item.radius);
+        });
+    },
+    drawKillFeed() {
+        let now = Date.now();
+        client.kills = client.kills.filter(kill => now - kill.time < global.killFeedSeconds * 1000);
+        if (client.kills.length === 0) {
+            return;
+        }
+        let font = graph.font;
+        graph.font = 'bold 14px sans-serif';
+        client.kills.forEach((kill, i) => {
+            graph.globalAlpha = Math.min(1, (global.killFeedSeconds * 1000 - (now - kill.time)) / 1000);
+            graph.fillStyle = kill.mine ? '#ff0000' : '#333333';
+            graph.fillText(kill.killer + ' ate ' + kill.victim, 10, 20 + i * 18);
+        });
+        graph.globalAlpha = 1;
+        graph.font = font;
+    },
+    drawAchievements() {
+        let now = Date.now();
+        client.achievements = client.achievements.filter(a => now - a.time < global.achievementSeconds * 1000);
+        if (client.achievements.length === 0) {
+            return;
+        }
+        let font = graph.font;
+        graph.textAlign = 'center';
+        client.achievements.forEach((a, i) => {
+            let y = 60 + i * 50;
+            graph.globalAlpha = Math.min(1, (global.achievementSeconds * 1000 - (now - a.time)) / 1000);
+            graph.fillStyle = '#f5a623';
+            graph.font = 'bold 20px sans-serif';
+            graph.fillText('achievement unlocked: ' + a.name, global.screenWidth / 2, y);
+            graph.fillStyle = '#333333';
+            graph.font = '14px sans-serif';
+            graph.fillText(a.description, global.screenWidth / 2, y + 20);
+        });
+        graph.globalAlpha = 1;
+        graph.textAlign = 'start';
+        graph.font = font;
+    },
+    drawDeath() {
+        let death = client.death;
+        let font = graph.font;
+        let x = global.screenWidth / 2, y = global.screenHeight / 2;
+        graph.textAlign = 'center';
+        graph.fillStyle = '#ff0000';
+        graph.font = 'bold 26px sans-serif';
+        graph.fillText(death.killer ? 'you were eaten by ' + death.killer : 'you died', x, y - 60);
+        graph.fillStyle = '#333333';
+        graph.font = 'bold 18px sans-serif';
+        graph.fillText('peak mass: ' + death.peakMass, x, y - 20);
+        graph.fillText('time alive: ' + Math.round(death.timeAlive / 1000) + 's', x, y + 5);
+        graph.fillText('foods eaten: ' + death.foodsEaten, x, y + 30);
+        graph.fillText('cells eaten: ' + death.cellsEaten, x, y + 55);
+        let wait = Math.ceil((death.respawnAt - Date.now()) / 1000);
+        graph.fillStyle = '#ff0000';
+        if (death.respawning) {
+            graph.fillText('respawning ...', x, y + 100);
+        } else if (wait > 0) {
+            graph.fillText('respawn in ' + wait + 's', x, y + 100);
+        } else {
+            graph.fillText('click or press enter to play again, esc to leave', x, y + 100);
+        }
+        graph.textAlign = 'start';
+        graph.font = font;
+    },
+    drawRIP() {
+        let font = graph.font;
+        graph.fillStyle = '#ff0000';
+        graph.font = 'bold 22px sans-serif';
+        let reason = client.error ? client.error : 'you are eaten or lose connect!';
+        graph.fillText(reason, global.screenWidth / 2 - 60, global.screenHeight / 2 - 15);
+        graph.fillText('game will exit after ' + global.ripWaitSeconds + ' seconds ...', global.screenWidth / 2 - 120, global.screenHeight / 2 + 15);
+        graph.font = font;
+    },
+};
+
+const handler = {
+    handle(message) {
+        let msgType = message.substring(0, 2);
+        let payload = message.substring(3);
+        switch (msgType) {
+            case ActionError:
+                handler.handleError(payload);
+                break;
+            case ActionPing:
+                handler.handlePing(payload);
+                break;
+            case ActionGameSetup:
+                handler.handleGameSetup(payload);
+                break;
+            case ActionChat:
+                handler.handleChat(payload);
+                break;
+            case ActionPlayerStatus:
+                handler.handlePlayerStatus(payload);
+                break;
+            case ActionLeaderBoard:
+                handler.handleLeaderBoard(payload);
+                break;
+            case ActionDeath:
+                handler.handleDeath(payload);
+                break;
+            case ActionKill:
+                handler.handleKill(payload);
+                break;
+            case ActionAchievement:
+                handler.handleAchievement(payload);
+                break;
+        }
+    },
+    handlePing(data) {
+        if (data !== '') {
+            sender.send(ActionPing, data);
+            return
+        }
+        if (client.pingTime) {
+            client.ping = (new Date()).valueOf() - client.pingTime;
+            client.pingTime = undefined;
+        }
+    },
+    handleGameSetup(data) {
+        let split = data.split("|");
+        global.gameWidth = parseFloat(split[0]);
+        global.gameHeight = parseFloat(split[1]);
+        global.screenWidth = parseFloat(split[2]);
+        global.screenHeight = parseFloat(split[3]);
+        global.virusColor = split[4];
+        global.roomName = split[5];
+        global.roomCode = split[6];
+        global.respawnDelay = parseInt(split[7]) || 0;
+        global.tickRate = parseInt(split[8]) || 60;
+        //a setup also follows a respawn
+        client.death = undefined;
+        if (global.roomCode) {
+            messager.append(`room [ ${global.roomName} ] code: ${global.roomCode}`, true);
+        } else {
+            messager.append(`room [ ${global.roomName} ]`, true);
+        }
+        canvas.setAttribute('width', global.screenWidth);
+        canvas.setAttribute('height', global.screenHeight);
+    },
+    handleChat(data) {
+        let type = data.substring(0, 1);
+        if ('0' === type) {
+            messager.append(data.substring(1), true)
+        } else {
+            messager.append(data.substring(1), false)
+        }
+    },
+    handleError(data) {
+        let index = data.indexOf('|');
+        if (index < 0) {
+            client.error = data;
+            return;
+        }
+        let code = parseInt(data.substring(0, index));
+        client.error = errorHints[code] || data.substring(index + 1);
+    },
+    handlePlayerStatus(data) {
+        let parsePlayer = data => {
+            let datas = data.split('|');
+            let index = 0;
+            let pDatas = datas[index].split(',');
+            let player = {
+                name: pDatas[0],
+                x: parseFloat(pDatas[1]),
+                y: parseFloat(pDatas[2]),
+                massTotal: parseFloat(pDatas[3]),
+                lastInput: parseInt(pDatas[4]),
+                tick: parseInt(pDatas[5]),
+                serverTime: parseInt(pDatas[6]),
+                visibleCells: [],
+                visibleFoods: [],
+                visibleMassFoods: [],
+                visibleViruses: [],
+            };
+            index++;
+
+            let cLen = parseInt(datas[index]);
+            index++;
+            for (let i = index; i < index + cLen; i++) {
+                let cDatas = datas[i].split(',');
+                player.visibleCells.push({
+                    name: cDatas[0],
+                    background: cDatas[1],
+                    textColor: cDatas[2],
+                    x: parseFloat(cDatas[3]),
+                    y: parseFloat(cDatas[4]),
+                    radius: parseFloat(cDatas[5]),
+                    id: cDatas[6],
+                    vx: parseFloat(cDatas[7]),
+                    vy: parseFloat(cDatas[8]),
+                    mine: cDatas[9] === '1',
+                    skin: cDatas[10] || '',
+                });
+            }
+            index += cLen;
+
+            let fLen = parseInt(datas[index]);
+            index++;
+            for (let i = index; i < index + fLen; i++) {
+                let fDatas = datas[i].split(',');
+                player.visibleFoods.push({
+                    x: parseFloat(fDatas[0]),
+                    y: parseFloat(fDatas[1]),
+                    radius: parseFloat(fDatas[2]),
+                    color: fDatas[3],
+                });
+            }
+            index += fLen;
+
+            let mfLen = parseInt(datas[index]);
+            index++;
+            for (let i = index; i < index + mfLen; i++) {
+                let mfDatas = datas[i].split(',');
+                player.visibleMassFoods.push({
+                    x: parseFloat(mfDatas[0]),
+                    y: parseFloat(mfDatas[1]),
+                    radius: parseFloat(mfDatas[2]),
+                    color: mfDatas[3],
+                });
+            }
+            index += mfLen;
+
+            let vLen = parseInt(datas[index]);
+            index++;
+            for (let i = index; i < index + vLen; i++) {
+                let vDatas = datas[i].split(',');
+                player.visibleViruses.push({
+                    x: parseFloat(vDatas[0]),
+                    y: parseFloat(vDatas[1]),
+                    radius: parseFloat(vDatas[2]),
+                });
+            }
+            return player;
+        };
+
+        if (client.death) {
+            return;
+        }
+        client.player = parsePlayer(data);
+        predictor.push(client.player);
+    },
+    handleAchievement(data) {
+        let parts = data.split('|');
+        client.achievements.push({
+            id: parts[0],
+            name: parts[1],
+            description: parts.slice(2).join('|'),
+            time: Date.now(),
+        });
+        messager.append('achievement unlocked: ' + parts[1], true);
+    },
+    handleKill(data) {
+        let index = data.indexOf('|');
+        let kill = {
+            killer: data.substring(0, index),
+            victim: data.substring(index + 1),
+            time: Date.now(),
+        };
+        let name = client.player ? client.player.name : undefined;
+        kill.mine = name !== undefined && (kill.killer === name || kill.victim === name);
+        client.kills.push(kill);
+        if (client.kills.length > global.killFeedSize) {
+            client.kills.shift();
+        }
+    },
+    handleDeath(data) {
+        let parts = data.split('|');
+        client.death = {
+            peakMass: parseInt(parts[0]),
+            timeAlive: parseInt(parts[1]),
+            foodsEaten: parseInt(parts[2]),
+            cellsEaten: parseInt(parts[3]),
+            killer: parts.slice(4).join('|'),
+            respawnAt: Date.now() + global.respawnDelay,
+            respawning: false,
+        };
+        client.player = undefined;
+        predictor.reset();
+    },
+    handleLeaderBoard(data) {
+        let parts = data.split('|');
+        let split = parts[2] ? parts[2].split(',') : [];
+        let status = '<span class="title">LeaderBoard</span>';
+        for (let i = 0; i < split.length; i++) {
+            status += '<br />';
+            if (client.player && split[i] === client.player.name) {
+                status += '<span class="me">' + (i + 1) + '. ' + split[i] + "</span>"
+            } else {
+                status += (i + 1) + '. ' + split[i];
+            }
+        }
+        document.getElementById('status').innerHTML = status
+    },
+};
+
+const sender = {
+    ping() {
+        sender.send(ActionPing)
+    },
+    send(msgType, payload = '') {
+        let ws = client.ws;
+        if (ws) {
+            ws.send(msgType + "|" + payload)
+        }
+    }
+};
+
+function bytesToSize(bytes) {
+    if (bytes === 0) return '0 B';
+    let k = 1000, // or 1024
+        sizes = ['B', 'KB', 'MB', 'GB', 'TB', 'PB', 'EB', 'ZB', 'YB'],
+        i = Math.floor(Math.log(bytes) / Math.log(k));
+
+    return (bytes / Math.pow(k, i)).toPrecision(3) + ' ' + sizes[i];
+}
+
+controller.init();
+lobby.init();
+messager.init();
\ No newline at end of file
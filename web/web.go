@@ -0,0 +1,19 @@
+//Package web holds the browser client, everything under static is served by the gateway
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed static
+var files embed.FS
+
+//Static is the embedded static directory, rooted at its content
+func Static() fs.FS {
+	static, e := fs.Sub(files, "static")
+	if e != nil {
+		panic(e)
+	}
+	return static
+}